@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// runJobInBatches creates the jobs batch by batch, starting with the canary
+// batch, and waits for each batch before starting the next. It aborts once the
+// canary fails or the failure threshold is crossed and records the targets
+// that were never attempted. An interrupted run stops before the next batch.
+func runJobInBatches(ctx context.Context, out io.Writer, opts runJobOptions, jobManager k8s.JobManagerInterface, logManager k8s.LogManagerInterface, manifests []*batchv1.Job, result *output.RunJobResult) error {
+	batches := k8s.PlanBatches(manifests, opts.batch)
+	limit := opts.batch.MaxFailures.Limit(len(manifests))
+
+	var results []k8s.JobResult
+	failures := 0
+	var abortErr error
+
+	for i, batch := range batches {
+		if err := ctx.Err(); err != nil {
+			result.SetJobResults(results)
+			return err
+		}
+
+		canary := i == 0 && opts.batch.Canary > 0
+		label := fmt.Sprintf("batch %d/%d", i+1, len(batches))
+		if canary {
+			label = "canary batch"
+		}
+		fmt.Fprintf(out, "\nStarting %s with %d targets...\n", label, len(batch))
+
+		created := submitJobs(ctx, out, jobManager, batch, opts.parallelism, result)
+		jobs := k8s.CreatedJobs(created)
+
+		batchFailures := len(batch) - len(jobs)
+		if len(jobs) > 0 {
+			if opts.follow {
+				fmt.Fprintf(out, "\nStreaming logs from %d jobs...\n", len(jobs))
+				followJobLogs(ctx, out, logManager, jobs, opts.jobNamespace, opts.logDir, opts.timeout)
+			}
+
+			fmt.Fprintf(out, "\nWaiting for %d jobs to finish...\n", len(jobs))
+			batchResults, err := jobManager.WaitForJobs(ctx, opts.jobNamespace, created, opts.timeout)
+			if err != nil {
+				result.SetJobResults(append(results, batchResults...))
+				return err
+			}
+			results = append(results, batchResults...)
+
+			for _, jobResult := range batchResults {
+				if !jobResult.Succeeded() {
+					batchFailures++
+				}
+			}
+		}
+		failures += batchFailures
+		fmt.Fprintf(out, "Finished %s: %d of %d targets failed (%d failures in total, %d tolerated)\n", label, batchFailures, len(batch), failures, limit)
+
+		switch {
+		case canary && batchFailures > 0:
+			abortErr = fmt.Errorf("canary failed on %d of %d targets; aborting", batchFailures, len(batch))
+		case failures > limit:
+			abortErr = fmt.Errorf("%d failures exceed --max-failures=%s; aborting", failures, opts.batch.MaxFailures)
+		}
+		if abortErr != nil {
+			for _, remaining := range batches[i+1:] {
+				for _, job := range remaining {
+					result.NotAttempted = append(result.NotAttempted, k8s.JobTargetName(job))
+				}
+			}
+			break
+		}
+	}
+
+	result.SetJobResults(results)
+	reportJobResults(ctx, out, opts, logManager, results, opts.jobNamespace)
+
+	if abortErr == nil {
+		return failureError(failures, len(manifests), fmt.Errorf("%d of %d targets did not succeed", failures, len(manifests)))
+	}
+
+	if len(result.NotAttempted) > 0 {
+		fmt.Fprintf(out, "\nNot attempted (%d):\n", len(result.NotAttempted))
+		for _, target := range result.NotAttempted {
+			fmt.Fprintf(out, "  - %s\n", target)
+		}
+	}
+	// Targets that were never attempted did not succeed either
+	return failureError(failures+len(result.NotAttempted), len(manifests), fmt.Errorf("%v; %d targets not attempted", abortErr, len(result.NotAttempted)))
+}
+
+// parseBatchOptions validates the batching flags and parses --max-failures
+func parseBatchOptions(opts *runJobOptions, maxFailures string) error {
+	if opts.batch.Canary < 0 || opts.batch.BatchSize < 0 {
+		return fmt.Errorf("--canary and --batch-size must not be negative")
+	}
+	if !opts.batch.Enabled() {
+		if maxFailures != "" {
+			return fmt.Errorf("--max-failures requires --batch-size or --canary")
+		}
+		return nil
+	}
+	if opts.execMode != execModeJob {
+		return fmt.Errorf("--batch-size and --canary are only supported with --exec-mode=%s", execModeJob)
+	}
+
+	threshold, err := k8s.ParseFailureThreshold(maxFailures)
+	if err != nil {
+		return err
+	}
+	opts.batch.MaxFailures = threshold
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newFinishingClientset returns a clientset whose jobs finish immediately,
+// failing on the given nodes
+func newFinishingClientset(failNodes map[string]bool) *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		obj, err := clientset.Tracker().Get(batchv1.SchemeGroupVersion.WithResource("jobs"), get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		job := obj.(*batchv1.Job).DeepCopy()
+		condition := batchv1.JobComplete
+		if failNodes[job.Annotations[k8s.NodeAnnotation]] {
+			condition = batchv1.JobFailed
+		}
+		job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		return true, job, nil
+	})
+	return clientset
+}
+
+func TestRunJobInBatches(t *testing.T) {
+	tests := []struct {
+		name             string
+		maxFailures      string
+		canary           int
+		batchSize        int
+		failNodes        map[string]bool
+		wantJobs         int
+		wantNotAttempted []string
+		wantCode         int
+	}{
+		{
+			name:      "all batches succeed",
+			canary:    1,
+			batchSize: 2,
+			wantJobs:  5,
+		},
+		{
+			name:             "canary failure aborts",
+			canary:           1,
+			batchSize:        2,
+			failNodes:        map[string]bool{"node-0": true},
+			wantJobs:         1,
+			wantNotAttempted: []string{"node-1", "node-2", "node-3", "node-4"},
+			wantCode:         exitTotalFailure,
+		},
+		{
+			name:             "threshold crossed",
+			canary:           1,
+			batchSize:        2,
+			failNodes:        map[string]bool{"node-2": true},
+			wantJobs:         3,
+			wantNotAttempted: []string{"node-3", "node-4"},
+			wantCode:         exitPartialFailure,
+		},
+		{
+			name:        "failures within threshold",
+			maxFailures: "40%",
+			batchSize:   2,
+			failNodes:   map[string]bool{"node-1": true, "node-4": true},
+			wantJobs:    5,
+			wantCode:    exitPartialFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := runJobOptions{
+				jobNamespace: "default",
+				execMode:     execModeJob,
+				timeout:      time.Second,
+				batch:        k8s.BatchOptions{Canary: tt.canary, BatchSize: tt.batchSize},
+			}
+			if err := parseBatchOptions(&opts, tt.maxFailures); err != nil {
+				t.Fatalf("parseBatchOptions() error = %v", err)
+			}
+
+			clientset := newFinishingClientset(tt.failNodes)
+			jobManager := k8s.NewJobManager(clientset)
+
+			var targets []k8s.JobTarget
+			for i := 0; i < 5; i++ {
+				targets = append(targets, k8s.JobTarget{Node: fmt.Sprintf("node-%d", i)})
+			}
+			manifests, err := jobManager.BuildJobs("task", targets, k8s.JobOptions{Namespace: "default", Image: "busybox"})
+			if err != nil {
+				t.Fatalf("BuildJobs() error = %v", err)
+			}
+
+			result := output.NewRunJobResult("deployment/web", "default", "default", nil)
+			err = runJobInBatches(context.TODO(), io.Discard, opts, jobManager, k8s.NewLogManager(clientset), manifests, result)
+			if (err != nil) != (tt.wantCode != 0) {
+				t.Fatalf("runJobInBatches() error = %v, want exit code %d", err, tt.wantCode)
+			}
+			if err != nil && exitCode(err) != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, exitCode(err))
+			}
+
+			jobs, _ := clientset.BatchV1().Jobs("default").List(context.TODO(), metav1.ListOptions{})
+			if len(jobs.Items) != tt.wantJobs || len(result.Jobs) != tt.wantJobs {
+				t.Errorf("Expected %d jobs, got %d created and %d recorded", tt.wantJobs, len(jobs.Items), len(result.Jobs))
+			}
+			if len(result.NotAttempted) != len(tt.wantNotAttempted) {
+				t.Fatalf("Expected not attempted %v, got %v", tt.wantNotAttempted, result.NotAttempted)
+			}
+			for i := range tt.wantNotAttempted {
+				if result.NotAttempted[i] != tt.wantNotAttempted[i] {
+					t.Errorf("Expected not attempted %v, got %v", tt.wantNotAttempted, result.NotAttempted)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBatchOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        runJobOptions
+		maxFailures string
+		expectError bool
+	}{
+		{name: "no batching", opts: runJobOptions{execMode: execModeJob}},
+		{name: "max failures without batching", opts: runJobOptions{execMode: execModeJob}, maxFailures: "1", expectError: true},
+		{name: "negative batch size", opts: runJobOptions{execMode: execModeJob, batch: k8s.BatchOptions{BatchSize: -1}}, expectError: true},
+		{name: "batching in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, batch: k8s.BatchOptions{Canary: 1}}, expectError: true},
+		{name: "invalid threshold", opts: runJobOptions{execMode: execModeJob, batch: k8s.BatchOptions{BatchSize: 2}}, maxFailures: "x", expectError: true},
+		{name: "percent threshold", opts: runJobOptions{execMode: execModeJob, batch: k8s.BatchOptions{BatchSize: 2}}, maxFailures: "10%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseBatchOptions(&tt.opts, tt.maxFailures)
+			if (err != nil) != tt.expectError {
+				t.Errorf("parseBatchOptions() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
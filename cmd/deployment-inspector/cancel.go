@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// cleanupTimeout bounds the deletion of created jobs after an interrupt. It is
+// shorter than the default 30s termination grace period of a CronJob pod.
+const cleanupTimeout = 20 * time.Second
+
+// cancelRun reports an interrupted run-job. Job creation has already stopped;
+// with --cleanup-on-cancel the jobs of this run are deleted as well. They are
+// found by their run ID label, so that jobs whose creation was still in flight
+// when the run was interrupted are not left behind.
+func cancelRun(out io.Writer, opts runJobOptions, runManager k8s.RunManagerInterface) error {
+	fmt.Fprintln(out, "\nInterrupted; no further jobs will be created")
+	if !opts.cleanupOnCancel {
+		return canceledError(fmt.Errorf("run-job canceled"))
+	}
+
+	fmt.Fprintf(out, "Deleting jobs created by run %s...\n", opts.runID)
+
+	// The run's context is already canceled, so cleanup gets its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	deleted, err := runManager.DeleteRun(ctx, opts.jobNamespace, opts.runID, metav1.DeletePropagationBackground)
+	for _, job := range deleted {
+		fmt.Fprintf(out, "Deleted job %s\n", job)
+	}
+	if err != nil {
+		return canceledError(fmt.Errorf("run-job canceled; deleted %d jobs: %v", len(deleted), err))
+	}
+	return canceledError(fmt.Errorf("run-job canceled; deleted %d jobs", len(deleted)))
+}
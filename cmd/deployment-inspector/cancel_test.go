@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCancelRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		cleanupOnCancel bool
+		wantRemaining   int
+	}{
+		{name: "keep created jobs", wantRemaining: 4},
+		{name: "cleanup on cancel", cleanupOnCancel: true, wantRemaining: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobOfRun := func(name, runID string) *batchv1.Job {
+				return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "jobs",
+					Labels:    map[string]string{k8s.RunIDLabel: runID},
+				}}
+			}
+			clientset := fake.NewSimpleClientset(
+				jobOfRun("task-abc-1", "abc"),
+				jobOfRun("task-abc-2", "abc"),
+				// Created by a request that was still in flight at the interrupt
+				jobOfRun("task-abc-3", "abc"),
+				// A job of another run is never deleted
+				jobOfRun("task-xyz-1", "xyz"),
+			)
+
+			opts := runJobOptions{jobNamespace: "jobs", runID: "abc", cleanupOnCancel: tt.cleanupOnCancel}
+			err := cancelRun(io.Discard, opts, k8s.NewRunManager(clientset))
+			if code := exitCode(err); code != exitCanceled {
+				t.Errorf("Expected exit code %d, got %d (%v)", exitCanceled, code, err)
+			}
+
+			jobs, _ := clientset.BatchV1().Jobs("jobs").List(context.TODO(), metav1.ListOptions{})
+			if len(jobs.Items) != tt.wantRemaining {
+				t.Errorf("Expected %d remaining jobs, got %d", tt.wantRemaining, len(jobs.Items))
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// dryRunClient renders the jobs locally without contacting the API server
+	dryRunClient = "client"
+	// dryRunServer submits the jobs with DryRun=All so that the API server validates them
+	dryRunServer = "server"
+)
+
+// parseDryRun parses the --dry-run flag. It returns "" when jobs should be created.
+func parseDryRun(value string) (string, error) {
+	switch value {
+	case "", "none":
+		return "", nil
+	case dryRunClient, dryRunServer:
+		return value, nil
+	default:
+		return "", fmt.Errorf("invalid dry-run mode %q (expected none, client or server)", value)
+	}
+}
+
+// validateDryRun rejects options that need the jobs to actually run
+func validateDryRun(opts runJobOptions) error {
+	if opts.dryRun == "" {
+		return nil
+	}
+	if opts.execMode != execModeJob {
+		return fmt.Errorf("--dry-run requires --exec-mode=%s", execModeJob)
+	}
+	if opts.wait || opts.follow || opts.collectLogs {
+		return fmt.Errorf("--dry-run cannot be combined with --wait, --follow, --collect-logs or --log-dir")
+	}
+	return nil
+}
+
+// dryRunJobs records the jobs that would be created instead of creating them.
+// A server dry run submits them with DryRun=All so that admission webhooks and
+// quota validate them, and records the jobs as returned by the API server.
+func dryRunJobs(ctx context.Context, out io.Writer, opts runJobOptions, jobManager k8s.JobManagerInterface, manifests []*batchv1.Job, result *output.RunJobResult) error {
+	result.DryRun = opts.dryRun
+
+	if opts.dryRun == dryRunClient {
+		for _, job := range manifests {
+			result.AddManifest(job)
+		}
+	} else {
+		for _, c := range jobManager.DryRunJobs(ctx, manifests, opts.parallelism) {
+			if c.Created() {
+				result.AddManifest(c.Object)
+				continue
+			}
+			if c.Category != k8s.ErrorCategoryCanceled {
+				fmt.Fprintf(out, "Job %s on %s was rejected [%s]: %v\n", c.Job, c.Target(), c.Category, c.Err)
+			}
+			result.AddFailure(c)
+		}
+	}
+
+	if opts.output.IsTable() {
+		if err := printDryRun(out, result); err != nil {
+			return err
+		}
+	}
+
+	rejected := len(manifests) - len(result.Manifests)
+	return failureError(rejected, len(manifests), fmt.Errorf("%d of %d jobs were rejected by the API server", rejected, len(manifests)))
+}
+
+// printDryRun prints the target nodes, the tolerations of the job pods, the
+// tolerations derived from node taints and the job manifests of a dry run
+func printDryRun(out io.Writer, result *output.RunJobResult) error {
+	fmt.Fprintf(out, "\nDry run (%s): %d jobs would be created in namespace %s\n", result.DryRun, len(result.Manifests), result.JobNamespace)
+
+	fmt.Fprintf(out, "\nNodes (%d):\n", len(result.Nodes))
+	for _, node := range result.Nodes {
+		fmt.Fprintf(out, "  - %s (%s)\n", node.Name, strings.Join(node.Pods, ", "))
+	}
+
+	fmt.Fprintf(out, "\nTolerations (%d):\n", len(result.Tolerations))
+	for _, toleration := range result.Tolerations {
+		fmt.Fprintf(out, "  - %s\n", formatToleration(toleration))
+	}
+
+	if len(result.NodeTaints) > 0 {
+		fmt.Fprintf(out, "\nNode taints (%d):\n", len(result.NodeTaints))
+		for _, taint := range result.NodeTaints {
+			if taint.Toleration != nil {
+				fmt.Fprintf(out, "  - %s %s: added toleration %s\n", taint.Node, taint.Taint, formatToleration(*taint.Toleration))
+			} else {
+				fmt.Fprintf(out, "  - %s %s: skipped (%s)\n", taint.Node, taint.Taint, taint.Reason)
+			}
+		}
+	}
+
+	fmt.Fprintln(out)
+	jobs := make([]*batchv1.Job, 0, len(result.Manifests))
+	for i := range result.Manifests {
+		jobs = append(jobs, &result.Manifests[i])
+	}
+	return printManifests(out, jobs)
+}
+
+// formatToleration formats a toleration in the key=value:effect form accepted by --tolerations
+func formatToleration(t corev1.Toleration) string {
+	s := t.Key
+	if t.Operator == corev1.TolerationOpExists {
+		if s == "" {
+			s = "*"
+		}
+	} else {
+		s += "=" + t.Value
+	}
+	if t.Effect != "" {
+		s += ":" + string(t.Effect)
+	}
+	if t.TolerationSeconds != nil {
+		s += fmt.Sprintf(" (%ds)", *t.TolerationSeconds)
+	}
+	return s
+}
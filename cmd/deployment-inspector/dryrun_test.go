@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestValidateDryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		opts        runJobOptions
+		expectError bool
+	}{
+		{name: "none", value: "none", opts: runJobOptions{execMode: execModeJob, wait: true}},
+		{name: "client", value: "client", opts: runJobOptions{execMode: execModeJob}},
+		{name: "server", value: "server", opts: runJobOptions{execMode: execModeJob}},
+		{name: "invalid mode", value: "local", expectError: true},
+		{name: "with wait", value: "client", opts: runJobOptions{execMode: execModeJob, wait: true}, expectError: true},
+		{name: "with collect logs", value: "server", opts: runJobOptions{execMode: execModeJob, collectLogs: true}, expectError: true},
+		{name: "ephemeral mode", value: "client", opts: runJobOptions{execMode: execModeEphemeral}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			tt.opts.dryRun, err = parseDryRun(tt.value)
+			if err == nil {
+				err = validateDryRun(tt.opts)
+			}
+			if (err != nil) != tt.expectError {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestDryRunJobs(t *testing.T) {
+	tests := []struct {
+		name          string
+		dryRun        string
+		rejectNode    string
+		wantManifests int
+		wantCode      int
+	}{
+		{name: "client", dryRun: dryRunClient, wantManifests: 2},
+		{name: "server", dryRun: dryRunServer, wantManifests: 2},
+		{name: "server rejects a job", dryRun: dryRunServer, rejectNode: "node2", wantManifests: 1, wantCode: exitPartialFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+				if tt.dryRun == dryRunClient {
+					t.Errorf("Unexpected create of job %s in a client dry run", job.Name)
+				}
+				if job.Annotations[k8s.NodeAnnotation] == tt.rejectNode {
+					return true, nil, apierrors.NewForbidden(batchv1.Resource("jobs"), job.Name, nil)
+				}
+				// The fake clientset ignores DryRun, so do not persist the job
+				return true, job, nil
+			})
+			jobManager := k8s.NewJobManager(clientset)
+
+			tolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "web", Effect: corev1.TaintEffectNoSchedule}}
+			manifests, err := jobManager.BuildJobs("task", []k8s.JobTarget{{Node: "node1"}, {Node: "node2"}}, k8s.JobOptions{
+				Namespace:   "jobs",
+				Image:       "busybox",
+				Tolerations: tolerations,
+			})
+			if err != nil {
+				t.Fatalf("BuildJobs() error = %v", err)
+			}
+
+			opts := runJobOptions{jobNamespace: "jobs", execMode: execModeJob, dryRun: tt.dryRun}
+			result := output.NewRunJobResult("deployment/web", "default", "jobs", nil)
+			var out bytes.Buffer
+			err = dryRunJobs(context.TODO(), &out, opts, jobManager, manifests, result)
+			if (err != nil) != (tt.wantCode != 0) {
+				t.Fatalf("dryRunJobs() error = %v, want exit code %d", err, tt.wantCode)
+			}
+			if err != nil && exitCode(err) != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, exitCode(err))
+			}
+
+			if len(result.Manifests) != tt.wantManifests || len(result.Jobs) != 0 {
+				t.Errorf("Expected %d manifests and no jobs, got %d and %d", tt.wantManifests, len(result.Manifests), len(result.Jobs))
+			}
+			if len(result.Tolerations) != 1 || result.DryRun != tt.dryRun {
+				t.Errorf("Unexpected dry run result %+v", result)
+			}
+			if !strings.Contains(out.String(), "dedicated=web:NoSchedule") || !strings.Contains(out.String(), "kind: Job") {
+				t.Errorf("Expected tolerations and manifests in output, got:\n%s", out.String())
+			}
+
+			jobs, _ := clientset.BatchV1().Jobs("jobs").List(context.TODO(), metav1.ListOptions{})
+			if len(jobs.Items) != 0 {
+				t.Errorf("Expected no jobs to be created, got %d", len(jobs.Items))
+			}
+		})
+	}
+}
+
+func TestRunJob_DryRunFilteredNode(t *testing.T) {
+	newPod := func(name, node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		newPod("web-1", "node1"),
+		newPod("web-2", "node2"),
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+	)
+
+	opts := runJobOptions{
+		workload:     k8s.WorkloadRef{Kind: k8s.KindSelector, Selector: "app=web"},
+		namespace:    "default",
+		jobNamespace: "jobs",
+		jobName:      "task",
+		image:        "busybox",
+		execMode:     execModeJob,
+		dryRun:       dryRunClient,
+		placement:    k8s.PlacementNodeName,
+		nodeFilter:   k8s.NodeFilter{ExcludeCordoned: true},
+	}
+	var out bytes.Buffer
+	result, err := runJob(context.TODO(), opts, clientset, &out)
+	if err != nil {
+		t.Fatalf("runJob() error = %v", err)
+	}
+
+	// The cordoned node2 is excluded, so it is not listed as a target node
+	if len(result.Nodes) != 1 || result.Nodes[0].Name != "node1" {
+		t.Errorf("Expected only node1 as target node, got %+v", result.Nodes)
+	}
+	if len(result.Manifests) != 1 || len(result.ExcludedNodes) != 1 {
+		t.Errorf("Expected 1 manifest and 1 excluded node, got %d and %+v", len(result.Manifests), result.ExcludedNodes)
+	}
+	if !strings.Contains(out.String(), "Nodes (1):\n  - node1 (web-1)\n") {
+		t.Errorf("Expected only node1 in the dry run output, got:\n%s", out.String())
+	}
+}
+
+func TestFormatToleration(t *testing.T) {
+	seconds := int64(300)
+	tests := []struct {
+		toleration corev1.Toleration
+		expected   string
+	}{
+		{toleration: corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "web", Effect: corev1.TaintEffectNoSchedule}, expected: "dedicated=web:NoSchedule"},
+		{toleration: corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &seconds}, expected: "gpu:NoExecute (300s)"},
+		{toleration: corev1.Toleration{Operator: corev1.TolerationOpExists}, expected: "*"},
+	}
+
+	for _, tt := range tests {
+		if got := formatToleration(tt.toleration); got != tt.expected {
+			t.Errorf("formatToleration(%+v) = %q, want %q", tt.toleration, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// execModeJob runs the command in a Job on each target node
+	execModeJob = "job"
+	// execModeEphemeral runs the command in a debug container attached to each target pod
+	execModeEphemeral = "ephemeral"
+)
+
+// validateExecMode rejects unknown exec modes and options that only apply to jobs
+func validateExecMode(opts runJobOptions) error {
+	if !opts.tolerateNodeTaints && (len(opts.taintFilter.Allow) > 0 || len(opts.taintFilter.Deny) > 0) {
+		return fmt.Errorf("--taint-allow and --taint-deny require --tolerate-node-taints")
+	}
+
+	switch opts.execMode {
+	case execModeJob:
+		if opts.targetContainer != "" {
+			return fmt.Errorf("--target-container requires --exec-mode=%s", execModeEphemeral)
+		}
+		return nil
+	case execModeEphemeral:
+		switch {
+		case opts.host:
+			return fmt.Errorf("--host is not supported with --exec-mode=%s", execModeEphemeral)
+		case opts.task != nil:
+			return fmt.Errorf("--task-file is not supported with --exec-mode=%s", execModeEphemeral)
+		case opts.showManifest:
+			return fmt.Errorf("--show-manifest is not supported with --exec-mode=%s", execModeEphemeral)
+		case opts.ifNotExists:
+			return fmt.Errorf("--if-not-exists is not supported with --exec-mode=%s", execModeEphemeral)
+		case opts.placement != "" && opts.placement != k8s.PlacementHostnameLabel:
+			return fmt.Errorf("--placement is not supported with --exec-mode=%s", execModeEphemeral)
+		case !opts.nodeFilter.IsEmpty():
+			return fmt.Errorf("node filters (--exclude-cordoned, --ready-nodes-only, --node-selector, --exclude-control-plane) are not supported with --exec-mode=%s", execModeEphemeral)
+		case opts.tolerateNodeTaints:
+			return fmt.Errorf("--tolerate-node-taints is not supported with --exec-mode=%s", execModeEphemeral)
+		case hasPodSettings(opts):
+			return fmt.Errorf("job pod settings (--backoff-limit, --active-deadline, --requests, --limits, --priority-class, --service-account, --image-pull-secrets, --image-pull-policy) are not supported with --exec-mode=%s", execModeEphemeral)
+		case opts.cleanupOnCancel:
+			return fmt.Errorf("--cleanup-on-cancel is not supported with --exec-mode=%s (ephemeral containers cannot be removed)", execModeEphemeral)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid exec mode %q (expected %s or %s)", opts.execMode, execModeJob, execModeEphemeral)
+	}
+}
+
+// runEphemeral attaches a debug container to every target pod and optionally
+// waits for them, reporting results the same way as jobs
+func runEphemeral(ctx context.Context, opts runJobOptions, clientset kubernetes.Interface, out io.Writer, pods []corev1.Pod, result *output.RunJobResult) error {
+	ephemeralManager := k8s.NewEphemeralManager(clientset)
+	logManager := k8s.NewLogManager(clientset)
+
+	fmt.Fprintf(out, "\nAttaching debug containers to %d pods in namespace %s...\n", len(pods), opts.namespace)
+
+	attachments := ephemeralManager.AttachDebugContainers(ctx, opts.jobName, pods, k8s.EphemeralOptions{
+		Image:           opts.image,
+		Command:         opts.command,
+		TargetContainer: opts.targetContainer,
+		RunID:           opts.runID,
+	})
+	for _, a := range attachments {
+		switch {
+		case a.Attached():
+			fmt.Fprintf(out, "Attached debug container %s to pod %s\n", a.Ref.Container, a.Ref.Pod)
+		case a.Category == k8s.ErrorCategoryCanceled:
+			// Not attempted; the interruption is reported once by cancelRun
+		default:
+			fmt.Fprintf(out, "Failed to attach debug container %s to pod %s [%s]: %v\n", a.Ref.Container, a.Ref.Pod, a.Category, a.Err)
+		}
+	}
+	result.AddAttachments(attachments)
+
+	refs := k8s.AttachedContainers(attachments)
+	attachFailed := len(pods) - len(refs)
+	if len(refs) == 0 {
+		fmt.Fprintln(out, "\nNo debug containers were attached")
+		return failureError(attachFailed, len(pods), fmt.Errorf("failed to attach all %d debug containers", len(pods)))
+	}
+	fmt.Fprintf(out, "\nSuccessfully attached %d debug containers\n", len(refs))
+
+	if opts.follow {
+		fmt.Fprintf(out, "\nStreaming logs from %d debug containers...\n", len(refs))
+		followEphemeralLogs(ctx, out, ephemeralManager, logManager, refs, opts.logDir, opts.timeout)
+	}
+
+	if !opts.wait && !opts.collectLogs {
+		return failureError(attachFailed, len(pods), fmt.Errorf("failed to attach %d of %d debug containers", attachFailed, len(pods)))
+	}
+
+	fmt.Fprintf(out, "\nWaiting for %d debug containers to finish...\n", len(refs))
+
+	results, err := ephemeralManager.WaitForEphemeralContainers(ctx, refs, opts.timeout)
+	if err != nil {
+		return err
+	}
+	result.SetJobResults(results)
+
+	failed := attachFailed + reportJobResults(ctx, out, opts, logManager, results, opts.namespace)
+	return failureError(failed, len(pods), fmt.Errorf("%d of %d targets did not succeed", failed, len(pods)))
+}
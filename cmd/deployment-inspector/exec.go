@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	"k8s.io/client-go/kubernetes"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec {[<kind>/]<name> | --selector <selector>} -- <command> [args...]",
+	Short: "Run a command inside every pod of a workload",
+	Args: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		if dash < 0 || dash == len(args) {
+			return fmt.Errorf("a command is required after --")
+		}
+		if dash > 1 {
+			return fmt.Errorf("expected at most one workload argument before --, got %d", dash)
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		var workloadArg string
+		if dash == 1 {
+			workloadArg = args[0]
+		}
+		workload, err := workloadFromArgs(workloadArg, viper.GetString("selector"))
+		if err != nil {
+			return err
+		}
+
+		opts := execOptions{
+			workload:  workload,
+			namespace: viper.GetString("namespace"),
+			exec: k8s.ExecOptions{
+				Command:     args[dash:],
+				Container:   viper.GetString("container"),
+				Parallelism: viper.GetInt("parallelism"),
+				Timeout:     viper.GetDuration("timeout"),
+			},
+		}
+		opts.output, err = output.ParseFormat(viper.GetString("output"))
+		if err != nil {
+			return err
+		}
+
+		// Arguments are valid at this point; command failures should not print usage
+		cmd.SilenceUsage = true
+
+		return runExec(cmd.Context(), opts)
+	},
+}
+
+// execOptions holds the settings of an exec invocation
+type execOptions struct {
+	workload  k8s.WorkloadRef
+	namespace string
+	exec      k8s.ExecOptions
+	output    output.Format
+}
+
+func runExec(ctx context.Context, opts execOptions) error {
+	config, err := k8s.NewClientWithOptions(clientOptions()).GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+
+	pods, err := k8s.NewWorkloadResolver(clientset).GetPods(ctx, opts.workload, opts.namespace)
+	if err != nil {
+		return err
+	}
+
+	// Keep stdout clean for machine-readable output by sending progress to stderr
+	var out io.Writer = os.Stdout
+	if !opts.output.IsTable() {
+		out = os.Stderr
+	}
+
+	if len(pods) == 0 {
+		fmt.Fprintf(out, "No pods found for %s in namespace %s\n", opts.workload, opts.namespace)
+		if !opts.output.IsTable() {
+			return output.Print(os.Stdout, opts.output, output.NewExecResult(opts.workload.String(), opts.namespace, opts.exec.Command, nil))
+		}
+		return nil
+	}
+
+	fmt.Fprintf(out, "Running %q in %d pods of %s...\n", strings.Join(opts.exec.Command, " "), len(pods), opts.workload)
+
+	results := k8s.NewExecManager(config).ExecInPods(ctx, pods, opts.exec)
+
+	if opts.output.IsTable() {
+		printExecOutput(os.Stdout, os.Stderr, results)
+		printExecResults(os.Stdout, results)
+	} else if err := output.Print(os.Stdout, opts.output, output.NewExecResult(opts.workload.String(), opts.namespace, opts.exec.Command, results)); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return canceledError(fmt.Errorf("exec canceled: %v", err))
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Succeeded() {
+			failed++
+		}
+	}
+	return failureError(failed, len(results), fmt.Errorf("command failed in %d of %d pods", failed, len(results)))
+}
+
+// printExecOutput prints each pod's captured stdout and stderr under a per-pod header
+func printExecOutput(stdout, stderr io.Writer, results []k8s.ExecResult) {
+	for _, result := range results {
+		fmt.Fprintf(stdout, "\n==> %s (node %s, container %s) <==\n", result.Pod, result.Node, result.Container)
+		writeWithNewline(stdout, result.Stdout)
+		writeWithNewline(stderr, result.Stderr)
+	}
+}
+
+// writeWithNewline writes data, terminating it with a newline if it is missing
+func writeWithNewline(w io.Writer, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	w.Write(data)
+	if data[len(data)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+}
+
+// printExecResults prints a per-pod summary table of exec results
+func printExecResults(out io.Writer, results []k8s.ExecResult) {
+	fmt.Fprintf(out, "\nExec results:\n")
+	fmt.Fprintln(out, strings.Repeat("-", 120))
+	fmt.Fprintf(out, "%-40s %-30s %-20s %-5s %-10s\n", "Pod", "Node", "Container", "Exit", "Duration")
+	fmt.Fprintln(out, strings.Repeat("-", 120))
+
+	for _, result := range results {
+		exitCode := "-"
+		if result.ExitCode >= 0 {
+			exitCode = fmt.Sprintf("%d", result.ExitCode)
+		}
+		fmt.Fprintf(out, "%-40s %-30s %-20s %-5s %-10s\n", result.Pod, result.Node, result.Container, exitCode, result.Duration.Round(time.Millisecond))
+		if result.Err != nil && result.ExitCode < 0 {
+			fmt.Fprintf(out, "  error: %v\n", result.Err)
+		}
+	}
+}
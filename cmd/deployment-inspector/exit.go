@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+)
+
+// Exit codes. Commands that act on several targets exit with exitPartialFailure
+// when only some of them failed and exitTotalFailure when none succeeded.
+// Commands interrupted with SIGINT or SIGTERM exit with exitCanceled.
+const (
+	exitError          = 1
+	exitPartialFailure = 2
+	exitTotalFailure   = 3
+	exitCanceled       = 130
+)
+
+// targetError is an error that carries the process exit code
+type targetError struct {
+	code int
+	err  error
+}
+
+func (e *targetError) Error() string {
+	return e.err.Error()
+}
+
+func (e *targetError) Unwrap() error {
+	return e.err
+}
+
+// failureError wraps err with the exit code for failed out of total targets.
+// It returns nil when nothing failed.
+func failureError(failed, total int, err error) error {
+	if failed == 0 {
+		return nil
+	}
+	code := exitPartialFailure
+	if failed >= total {
+		code = exitTotalFailure
+	}
+	return &targetError{code: code, err: err}
+}
+
+// canceledError wraps err with the exit code of an interrupted command
+func canceledError(err error) error {
+	return &targetError{code: exitCanceled, err: err}
+}
+
+// exitCode returns the process exit code for an error returned by a command
+func exitCode(err error) int {
+	var te *targetError
+	if errors.As(err, &te) {
+		return te.code
+	}
+	return exitError
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFailureError(t *testing.T) {
+	tests := []struct {
+		name     string
+		failed   int
+		total    int
+		wantNil  bool
+		wantCode int
+	}{
+		{name: "no failures", failed: 0, total: 3, wantNil: true},
+		{name: "partial failure", failed: 1, total: 3, wantCode: exitPartialFailure},
+		{name: "total failure", failed: 3, total: 3, wantCode: exitTotalFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := failureError(tt.failed, tt.total, fmt.Errorf("%d of %d targets failed", tt.failed, tt.total))
+			if (err == nil) != tt.wantNil {
+				t.Fatalf("failureError() = %v, want nil %v", err, tt.wantNil)
+			}
+			if err == nil {
+				return
+			}
+			if code := exitCode(err); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, code)
+			}
+			// The code survives wrapping
+			if code := exitCode(fmt.Errorf("cluster a: %w", err)); code != tt.wantCode {
+				t.Errorf("Expected wrapped exit code %d, got %d", tt.wantCode, code)
+			}
+		})
+	}
+
+	if code := exitCode(fmt.Errorf("invalid flag")); code != exitError {
+		t.Errorf("Expected exit code %d for plain errors, got %d", exitError, code)
+	}
+}
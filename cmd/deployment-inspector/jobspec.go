@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// parseJobSettings converts the --ttl, --backoff-limit and --active-deadline flags.
+// A negative backoff limit and a zero deadline keep the Kubernetes defaults.
+func parseJobSettings(ttl time.Duration, backoffLimit int, activeDeadline time.Duration) (k8s.JobSettings, error) {
+	var settings k8s.JobSettings
+
+	if ttl < 0 {
+		return settings, fmt.Errorf("--ttl must not be negative")
+	}
+	ttlSeconds := int32(ttl / time.Second)
+	settings.TTLSecondsAfterFinished = &ttlSeconds
+
+	if backoffLimit >= 0 {
+		limit := int32(backoffLimit)
+		settings.BackoffLimit = &limit
+	}
+
+	if activeDeadline < 0 {
+		return settings, fmt.Errorf("--active-deadline must not be negative")
+	}
+	if activeDeadline > 0 {
+		if activeDeadline < time.Second {
+			return settings, fmt.Errorf("--active-deadline must be at least 1s")
+		}
+		seconds := int64(activeDeadline / time.Second)
+		settings.ActiveDeadlineSeconds = &seconds
+	}
+	return settings, nil
+}
+
+// parseResourceList parses resource quantities in name=quantity form, e.g. cpu=100m,memory=64Mi
+func parseResourceList(value string) (corev1.ResourceList, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	resources := corev1.ResourceList{}
+	for _, part := range strings.Split(value, ",") {
+		name, quantity, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid resource %q (expected name=quantity, e.g. cpu=100m)", part)
+		}
+		parsed, err := resource.ParseQuantity(quantity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity for %s: %v", name, err)
+		}
+		resources[corev1.ResourceName(name)] = parsed
+	}
+	return resources, nil
+}
+
+// parsePullPolicy parses the --image-pull-policy flag; an empty value keeps the default
+func parsePullPolicy(value string) (corev1.PullPolicy, error) {
+	switch policy := corev1.PullPolicy(value); policy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid image pull policy %q (expected Always, IfNotPresent or Never)", value)
+	}
+}
+
+// imagePullSecrets converts secret names to pod image pull secret references
+func imagePullSecrets(names []string) []corev1.LocalObjectReference {
+	var refs []corev1.LocalObjectReference
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			refs = append(refs, corev1.LocalObjectReference{Name: name})
+		}
+	}
+	return refs
+}
+
+// hasPodSettings reports whether any setting that only applies to job pods was given
+func hasPodSettings(opts runJobOptions) bool {
+	return opts.settings.BackoffLimit != nil || opts.settings.ActiveDeadlineSeconds != nil ||
+		len(opts.resources.Requests) > 0 || len(opts.resources.Limits) > 0 ||
+		opts.pullPolicy != "" || opts.priorityClass != "" || opts.serviceAccount != "" || len(opts.pullSecrets) > 0
+}
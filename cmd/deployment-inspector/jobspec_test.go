@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseJobSettings(t *testing.T) {
+	tests := []struct {
+		name           string
+		ttl            time.Duration
+		backoffLimit   int
+		activeDeadline time.Duration
+		wantTTL        int32
+		wantBackoff    *int32
+		wantDeadline   *int64
+		expectError    bool
+	}{
+		{name: "defaults", ttl: 5 * time.Minute, backoffLimit: -1, wantTTL: 300},
+		{name: "all set", ttl: 0, backoffLimit: 0, activeDeadline: 10 * time.Minute, wantTTL: 0, wantBackoff: int32Ptr(0), wantDeadline: int64Ptr(600)},
+		{name: "negative ttl", ttl: -time.Second, backoffLimit: -1, expectError: true},
+		{name: "negative deadline", ttl: time.Minute, backoffLimit: -1, activeDeadline: -time.Second, expectError: true},
+		{name: "sub-second deadline", ttl: time.Minute, backoffLimit: -1, activeDeadline: time.Millisecond, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := parseJobSettings(tt.ttl, tt.backoffLimit, tt.activeDeadline)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseJobSettings() error = %v, expectError %v", err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+			if *settings.TTLSecondsAfterFinished != tt.wantTTL {
+				t.Errorf("Expected TTL %d, got %d", tt.wantTTL, *settings.TTLSecondsAfterFinished)
+			}
+			if (settings.BackoffLimit == nil) != (tt.wantBackoff == nil) || (tt.wantBackoff != nil && *settings.BackoffLimit != *tt.wantBackoff) {
+				t.Errorf("Expected backoff limit %v, got %v", tt.wantBackoff, settings.BackoffLimit)
+			}
+			if (settings.ActiveDeadlineSeconds == nil) != (tt.wantDeadline == nil) || (tt.wantDeadline != nil && *settings.ActiveDeadlineSeconds != *tt.wantDeadline) {
+				t.Errorf("Expected deadline %v, got %v", tt.wantDeadline, settings.ActiveDeadlineSeconds)
+			}
+		})
+	}
+}
+
+func TestParseResourceList(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    map[corev1.ResourceName]string
+		expectError bool
+	}{
+		{name: "empty", input: ""},
+		{name: "cpu and memory", input: "cpu=100m, memory=64Mi", expected: map[corev1.ResourceName]string{corev1.ResourceCPU: "100m", corev1.ResourceMemory: "64Mi"}},
+		{name: "extended resource", input: "nvidia.com/gpu=1", expected: map[corev1.ResourceName]string{"nvidia.com/gpu": "1"}},
+		{name: "missing quantity", input: "cpu", expectError: true},
+		{name: "invalid quantity", input: "memory=lots", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resources, err := parseResourceList(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parseResourceList() error = %v, expectError %v", err, tt.expectError)
+			}
+			if len(resources) != len(tt.expected) {
+				t.Fatalf("Expected %d resources, got %v", len(tt.expected), resources)
+			}
+			for name, want := range tt.expected {
+				if got := resources[name]; got.String() != want {
+					t.Errorf("Expected %s=%s, got %s", name, want, got.String())
+				}
+			}
+		})
+	}
+}
+
+func TestParsePullPolicy(t *testing.T) {
+	for _, value := range []string{"", "Always", "IfNotPresent", "Never"} {
+		if _, err := parsePullPolicy(value); err != nil {
+			t.Errorf("parsePullPolicy(%q) error = %v", value, err)
+		}
+	}
+	if _, err := parsePullPolicy("always"); err == nil {
+		t.Error("Expected an error for an invalid pull policy")
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+)
+
+// followJobLogs streams the logs of every job pod concurrently, prefixing each
+// line with the node (and target pod in per-pod mode). It returns once all streams have ended.
+func followJobLogs(ctx context.Context, out io.Writer, logManager k8s.LogManagerInterface, jobs []string, namespace, logDir string, timeout time.Duration) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job string) {
+			defer wg.Done()
+
+			pod, err := logManager.WaitForJobPod(ctx, job, namespace, timeout)
+			if err != nil {
+				log.Printf("Warning: %v", err)
+				return
+			}
+
+			target := k8s.TargetName(pod.Spec.NodeName, pod.Annotations[k8s.PodAnnotation])
+			pw := k8s.NewPrefixWriter(out, &mu, fmt.Sprintf("[%s] ", target))
+			defer pw.Flush()
+
+			var w io.Writer = pw
+			if logDir != "" {
+				f, err := createNodeLogFile(logDir, target)
+				if err != nil {
+					log.Printf("Warning: %v", err)
+				} else {
+					defer f.Close()
+					w = io.MultiWriter(pw, f)
+				}
+			}
+
+			if err := logManager.StreamPodLogs(ctx, pod.Name, namespace, k8s.JobContainerName, true, w); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}(job)
+	}
+
+	wg.Wait()
+}
+
+// followEphemeralLogs streams the logs of every debug container concurrently,
+// prefixing each line with its node and pod. It returns once all streams have ended.
+func followEphemeralLogs(ctx context.Context, out io.Writer, ephemeralManager k8s.EphemeralManagerInterface, logManager k8s.LogManagerInterface, refs []k8s.EphemeralContainerRef, logDir string, timeout time.Duration) {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, ref := range refs {
+		wg.Add(1)
+		go func(ref k8s.EphemeralContainerRef) {
+			defer wg.Done()
+
+			if err := ephemeralManager.WaitForEphemeralContainerStart(ctx, ref, timeout); err != nil {
+				log.Printf("Warning: %v", err)
+				return
+			}
+
+			target := k8s.TargetName(ref.Node, ref.Pod)
+			pw := k8s.NewPrefixWriter(out, &mu, fmt.Sprintf("[%s] ", target))
+			defer pw.Flush()
+
+			var w io.Writer = pw
+			if logDir != "" {
+				f, err := createNodeLogFile(logDir, target)
+				if err != nil {
+					log.Printf("Warning: %v", err)
+				} else {
+					defer f.Close()
+					w = io.MultiWriter(pw, f)
+				}
+			}
+
+			if err := logManager.StreamPodLogs(ctx, ref.Pod, ref.Namespace, ref.Container, true, w); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}(ref)
+	}
+
+	wg.Wait()
+}
+
+// collectJobLogs prints the logs of finished jobs (or debug containers) grouped per target and
+// optionally writes each node's log to logDir
+func collectJobLogs(ctx context.Context, out io.Writer, logManager k8s.LogManagerInterface, results []k8s.JobResult, namespace, logDir string) {
+	for _, result := range results {
+		fmt.Fprintf(out, "\n==> Logs from %s (job %s) <==\n", describeTarget(result), result.Job)
+		if result.Pod == "" {
+			fmt.Fprintln(out, "(no pod found)")
+			continue
+		}
+
+		container := result.Container
+		if container == "" {
+			container = k8s.JobContainerName
+		}
+
+		var buf bytes.Buffer
+		if err := logManager.StreamPodLogs(ctx, result.Pod, namespace, container, false, &buf); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+
+		fmt.Fprint(out, buf.String())
+		if buf.Len() > 0 && !strings.HasSuffix(buf.String(), "\n") {
+			fmt.Fprintln(out)
+		}
+
+		if logDir != "" {
+			if err := writeNodeLog(logDir, result.Target(), buf.Bytes()); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+// describeTarget describes the target of a job result for log headers
+func describeTarget(result k8s.JobResult) string {
+	if result.TargetPod != "" {
+		return fmt.Sprintf("pod %s on node %s", result.TargetPod, result.Node)
+	}
+	return "node " + result.Node
+}
+
+// createNodeLogFile creates <logDir>/<target>.log, creating logDir if needed.
+// Per-pod targets (node/pod) are written as <node>_<pod>.log.
+func createNodeLogFile(logDir, target string) (*os.File, error) {
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory %s: %v", logDir, err)
+	}
+	path := filepath.Join(logDir, strings.ReplaceAll(target, "/", "_")+".log")
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file %s: %v", path, err)
+	}
+	return f, nil
+}
+
+// writeNodeLog writes data to <logDir>/<target>.log
+func writeNodeLog(logDir, target string, data []byte) error {
+	f, err := createNodeLogFile(logDir, target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write log file for %s: %v", target, err)
+	}
+	return nil
+}
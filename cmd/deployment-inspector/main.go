@@ -1,74 +1,237 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
 )
 
+// version is the tool version, set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
+// hostModeWarning is printed whenever --host is used
+const hostModeWarning = "WARNING: --host creates privileged pods that share the host PID and network namespaces\n" +
+	"and mount the node root filesystem read-only at " + k8s.HostRootMountPath + ". They can see and affect every process on the node."
+
 var (
 	rootCmd = &cobra.Command{
-		Use:   "deployment-inspector",
-		Short: "A tool to inspect Kubernetes deployments and run jobs on their nodes",
+		Use:     "deployment-inspector",
+		Short:   "A tool to inspect Kubernetes deployments and run jobs on their nodes",
+		Version: version,
 		Long: `deployment-inspector is a CLI tool that helps you inspect Kubernetes deployments
-and run jobs on the nodes where deployment pods are running.`,
+and run jobs on the nodes where deployment pods are running.
+
+Targets are given as kind/name (deployment/web, statefulset/redis, ds/fluentd,
+rs/web-5d9c7) or as a raw label selector with --selector. A bare name refers
+to a Deployment.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Bind the flags of the command being executed so that flags shared
+			// between commands resolve to the invoked command's values
+			return viper.BindPFlags(cmd.Flags())
+		},
 	}
 
 	listCmd = &cobra.Command{
-		Use:   "list <deployment-name>",
-		Short: "List pods and nodes for a deployment",
-		Args:  cobra.ExactArgs(1),
+		Use:   "list [<kind>/]<name> | --selector <selector>",
+		Short: "List pods and nodes for a workload",
+		Args:  cobra.RangeArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			deploymentName := args[0]
+			var workloadArg string
+			if len(args) == 1 {
+				workloadArg = args[0]
+			}
+			workload, err := workloadFromArgs(workloadArg, viper.GetString("selector"))
+			if err != nil {
+				return err
+			}
+			format, err := output.ParseFormat(viper.GetString("output"))
+			if err != nil {
+				return err
+			}
+			podFilter, err := parsePodFilterFlags()
+			if err != nil {
+				return err
+			}
 			namespace := viper.GetString("namespace")
-			return listPodsAndNodes(deploymentName, namespace)
+			return listPodsAndNodes(cmd.Context(), workload, namespace, podFilter, format)
 		},
 	}
 
 	runJobCmd = &cobra.Command{
-		Use:   "run-job <deployment-name> <job-name>",
-		Short: "Run a job on nodes where deployment pods are running",
-		Args:  cobra.ExactArgs(2),
+		Use:   "run-job {[<kind>/]<name> | --selector <selector>} <job-name>",
+		Short: "Run a job on nodes where workload pods are running",
+		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			deploymentName := args[0]
-			jobName := args[1]
-			namespace := viper.GetString("namespace")
-			jobNamespace := viper.GetString("job-namespace")
-			image := viper.GetString("image")
+			var workloadArg string
+			if len(args) == 2 {
+				workloadArg = args[0]
+			}
+			workload, err := workloadFromArgs(workloadArg, viper.GetString("selector"))
+			if err != nil {
+				return err
+			}
+
+			opts := runJobOptions{
+				workload:        workload,
+				jobName:         args[len(args)-1],
+				namespace:       viper.GetString("namespace"),
+				jobNamespace:    viper.GetString("job-namespace"),
+				image:           viper.GetString("image"),
+				wait:            viper.GetBool("wait"),
+				timeout:         viper.GetDuration("timeout"),
+				follow:          viper.GetBool("follow"),
+				collectLogs:     viper.GetBool("collect-logs"),
+				logDir:          viper.GetString("log-dir"),
+				host:            viper.GetBool("host"),
+				showManifest:    viper.GetBool("show-manifest"),
+				perPod:          viper.GetBool("per-pod"),
+				runID:           viper.GetString("run-id"),
+				ifNotExists:     viper.GetBool("if-not-exists"),
+				cleanupOnCancel: viper.GetBool("cleanup-on-cancel"),
+				parallelism:     viper.GetInt("parallelism"),
+				batch: k8s.BatchOptions{
+					Canary:    viper.GetInt("canary"),
+					BatchSize: viper.GetInt("batch-size"),
+				},
+				execMode:           viper.GetString("exec-mode"),
+				targetContainer:    viper.GetString("target-container"),
+				priorityClass:      viper.GetString("priority-class"),
+				serviceAccount:     viper.GetString("service-account"),
+				pullSecrets:        imagePullSecrets(viper.GetStringSlice("image-pull-secrets")),
+				tolerateNodeTaints: viper.GetBool("tolerate-node-taints"),
+				taintFilter: k8s.TaintFilter{
+					Allow: viper.GetStringSlice("taint-allow"),
+					Deny:  viper.GetStringSlice("taint-deny"),
+				},
+				nodeFilter: k8s.NodeFilter{
+					ExcludeCordoned:     viper.GetBool("exclude-cordoned"),
+					ReadyOnly:           viper.GetBool("ready-nodes-only"),
+					ExcludeControlPlane: viper.GetBool("exclude-control-plane"),
+				},
+			}
+			opts.output, err = output.ParseFormat(viper.GetString("output"))
+			if err != nil {
+				return err
+			}
+			opts.contexts, err = targetContexts(viper.GetStringSlice("contexts"), viper.GetBool("all-contexts"), viper.GetString("context"))
+			if err != nil {
+				return err
+			}
 			commandStr := viper.GetString("command")
 			tolerationsStr := viper.GetString("tolerations")
 
+			// Writing log files without following requires collecting the logs afterwards
+			if opts.logDir != "" && !opts.follow {
+				opts.collectLogs = true
+			}
+
 			// If job namespace is not specified, use the deployment namespace
-			if jobNamespace == "" {
-				jobNamespace = namespace
+			if opts.jobNamespace == "" {
+				opts.jobNamespace = opts.namespace
 			}
 
-			var command []string
 			if commandStr != "" {
-				command = strings.Split(commandStr, ",")
-				for i := range command {
-					command[i] = strings.TrimSpace(command[i])
+				opts.command = strings.Split(commandStr, ",")
+				for i := range opts.command {
+					opts.command[i] = strings.TrimSpace(opts.command[i])
+				}
+			}
+
+			if taskFile := viper.GetString("task-file"); taskFile != "" {
+				opts.task, err = k8s.LoadTaskFile(taskFile)
+				if err != nil {
+					return err
 				}
 			}
 
 			// Parse tolerations from JSON string or simple format
-			var tolerations []corev1.Toleration
 			if tolerationsStr != "" {
 				var err error
-				tolerations, err = parseTolerations(tolerationsStr)
+				opts.tolerations, err = parseTolerations(tolerationsStr)
 				if err != nil {
 					return fmt.Errorf("failed to parse tolerations: %v", err)
 				}
 			}
 
-			return runJobOnNodes(deploymentName, jobName, namespace, jobNamespace, image, command, tolerations)
+			opts.settings, err = parseJobSettings(viper.GetDuration("ttl"), viper.GetInt("backoff-limit"), viper.GetDuration("active-deadline"))
+			if err != nil {
+				return err
+			}
+			if opts.resources.Requests, err = parseResourceList(viper.GetString("requests")); err != nil {
+				return fmt.Errorf("failed to parse --requests: %v", err)
+			}
+			if opts.resources.Limits, err = parseResourceList(viper.GetString("limits")); err != nil {
+				return fmt.Errorf("failed to parse --limits: %v", err)
+			}
+			opts.pullPolicy, err = parsePullPolicy(viper.GetString("image-pull-policy"))
+			if err != nil {
+				return err
+			}
+			opts.placement, err = k8s.ParsePlacement(viper.GetString("placement"))
+			if err != nil {
+				return err
+			}
+			opts.podFilter, err = parsePodFilterFlags()
+			if err != nil {
+				return err
+			}
+			if value := viper.GetString("revision"); value != "" {
+				opts.revision, err = k8s.ParseRevisionSelector(value)
+				if err != nil {
+					return err
+				}
+			}
+			if err := validateRevision(opts); err != nil {
+				return err
+			}
+			opts.nodeFilter.Selector, err = k8s.ParseNodeSelector(viper.GetString("node-selector"))
+			if err != nil {
+				return err
+			}
+
+			if err := validateExecMode(opts); err != nil {
+				return err
+			}
+			opts.dryRun, err = parseDryRun(viper.GetString("dry-run"))
+			if err != nil {
+				return err
+			}
+			if err := validateDryRun(opts); err != nil {
+				return err
+			}
+			// One run ID is shared by every cluster so that the run can be found everywhere
+			if opts.runID == "" {
+				opts.runID = k8s.NewRunID()
+			} else if err := k8s.ValidateRunID(opts.runID); err != nil {
+				return err
+			}
+			if err := parseBatchOptions(&opts, viper.GetString("max-failures")); err != nil {
+				return err
+			}
+
+			// Arguments are valid at this point; job failures should not print usage
+			cmd.SilenceUsage = true
+
+			if opts.host {
+				fmt.Fprintln(os.Stderr, hostModeWarning)
+			}
+
+			return runJobOnNodes(cmd.Context(), opts)
 		},
 	}
 )
@@ -76,22 +239,125 @@ and run jobs on the nodes where deployment pods are running.`,
 func init() {
 	// Persistent flags available to all commands
 	rootCmd.PersistentFlags().StringP("namespace", "n", "default", "Kubernetes namespace")
-	viper.BindPFlag("namespace", rootCmd.PersistentFlags().Lookup("namespace"))
+	rootCmd.PersistentFlags().String("kubeconfig", "", "Path to the kubeconfig file (defaults to $KUBECONFIG or ~/.kube/config)")
+	rootCmd.PersistentFlags().String("context", "", "Name of the kubeconfig context to use")
+	rootCmd.PersistentFlags().String("as", "", "Username to impersonate for the operation")
+	rootCmd.PersistentFlags().StringArray("as-group", nil, "Group to impersonate for the operation (can be repeated)")
+	rootCmd.PersistentFlags().Duration("request-timeout", 0, "Timeout for a single API request (0 means no timeout)")
+	rootCmd.PersistentFlags().Float32("qps", 50, "Maximum queries per second to the Kubernetes API server")
+	rootCmd.PersistentFlags().Int("burst", 100, "Maximum burst of queries to the Kubernetes API server")
+	rootCmd.PersistentFlags().Bool("in-cluster", true, "Use the in-cluster config when running inside a pod and no kubeconfig or context is given")
+
+	// List specific flags
+	listCmd.Flags().StringP("selector", "l", "", "Label selector for target pods (instead of a workload argument)")
+	listCmd.Flags().StringP("output", "o", "", "Output format: json|yaml|wide|name|go-template=...|jsonpath=...")
+	addPodFilterFlags(listCmd)
 
 	// Run-job specific flags
+	runJobCmd.Flags().StringP("selector", "l", "", "Label selector for target pods (instead of a workload argument)")
+	runJobCmd.Flags().StringP("output", "o", "", "Output format: json|yaml|wide|name|go-template=...|jsonpath=...")
+	runJobCmd.Flags().StringSlice("contexts", nil, "Run in each of these kubeconfig contexts concurrently (comma-separated)")
+	runJobCmd.Flags().Bool("all-contexts", false, "Run in every context of the kubeconfig concurrently")
 	runJobCmd.Flags().StringP("job-namespace", "j", "", "Kubernetes namespace for job (defaults to deployment namespace)")
 	runJobCmd.Flags().StringP("image", "i", "busybox", "Container image for the job")
 	runJobCmd.Flags().StringP("command", "c", "", "Command to run in the job (comma-separated)")
+	runJobCmd.Flags().String("task-file", "", "YAML or JSON file describing the job container and pod template (templated per node)")
+	runJobCmd.Flags().String("exec-mode", execModeJob, "How to run the command: job (a Job per node) or ephemeral (a debug container in each target pod)")
+	runJobCmd.Flags().String("target-container", "", "Container whose process namespace the ephemeral debug container shares (defaults to the pod's first container)")
+	addPodFilterFlags(runJobCmd)
+	runJobCmd.Flags().String("revision", "", "Only target pods of this deployment revision: a revision number, new (the newest) or old (every other)")
+	runJobCmd.Flags().String("placement", string(k8s.PlacementHostnameLabel), "How job pods are pinned to their node: hostname-label (the node's kubernetes.io/hostname label), node-name-affinity (node affinity on metadata.name) or node-name (spec.nodeName, bypassing the scheduler)")
+	runJobCmd.Flags().Bool("exclude-cordoned", false, "Do not create jobs on cordoned (unschedulable) nodes")
+	runJobCmd.Flags().Bool("ready-nodes-only", false, "Only create jobs on nodes whose Ready condition is True")
+	runJobCmd.Flags().String("node-selector", "", "Only create jobs on nodes matching this label selector (e.g. zone=us-east-1a)")
+	runJobCmd.Flags().Bool("exclude-control-plane", false, "Do not create jobs on control-plane nodes")
+	runJobCmd.Flags().Bool("tolerate-node-taints", false, "Add a toleration for each taint of a job's target node")
+	runJobCmd.Flags().StringSlice("taint-allow", nil, "Only tolerate node taints with these keys (comma-separated; a trailing * matches a prefix)")
+	runJobCmd.Flags().StringSlice("taint-deny", nil, "Never tolerate node taints with these keys (comma-separated; a trailing * matches a prefix)")
+	runJobCmd.Flags().Duration("ttl", 5*time.Minute, "Time after which finished jobs are deleted (ttlSecondsAfterFinished)")
+	runJobCmd.Flags().Int("backoff-limit", -1, "Number of retries before a job is marked failed (-1 keeps the Kubernetes default of 6)")
+	runJobCmd.Flags().Duration("active-deadline", 0, "Maximum time a job may run before it is terminated (0 means no deadline)")
+	runJobCmd.Flags().String("requests", "", "Resource requests of the job container, e.g. cpu=100m,memory=64Mi")
+	runJobCmd.Flags().String("limits", "", "Resource limits of the job container, e.g. cpu=200m,memory=128Mi")
+	runJobCmd.Flags().String("priority-class", "", "Priority class of the job pods")
+	runJobCmd.Flags().String("service-account", "", "Service account of the job pods")
+	runJobCmd.Flags().StringSlice("image-pull-secrets", nil, "Image pull secrets of the job pods (comma-separated)")
+	runJobCmd.Flags().String("image-pull-policy", "", "Image pull policy of the job container: Always, IfNotPresent or Never")
+	runJobCmd.Flags().Int("parallelism", 10, "Maximum number of jobs to create concurrently")
+	runJobCmd.Flags().Int("canary", 0, "Run on this many targets first and continue only if they all succeed")
+	runJobCmd.Flags().Int("batch-size", 0, "Run on this many targets at a time, waiting for each batch to finish (0 runs all at once)")
+	runJobCmd.Flags().String("max-failures", "", "Failed targets tolerated before a batched run aborts, as a count or a percentage (default 0)")
+	runJobCmd.Flags().Bool("per-pod", false, "Create one job per target pod on that pod's node instead of one per node")
+	runJobCmd.Flags().Bool("host", false, "Run privileged jobs in the host PID and network namespaces with the node root filesystem at "+k8s.HostRootMountPath)
+	runJobCmd.Flags().String("run-id", "", "ID shared by the jobs of this run and used in their names (generated if empty)")
+	runJobCmd.Flags().Bool("if-not-exists", false, "Skip targets that already have an active job for the same job name and workload")
+	runJobCmd.Flags().Bool("cleanup-on-cancel", false, "Delete the jobs created by this run when it is interrupted with SIGINT or SIGTERM")
+	runJobCmd.Flags().String("dry-run", "none", "Only print the jobs that would be created (client) or also validate them with a server-side dry run (server)")
+	runJobCmd.Flags().Bool("show-manifest", false, "Print the generated job manifests as YAML before creating them")
 	runJobCmd.Flags().StringP("tolerations", "t", "", "Tolerations for the job pods (JSON format or key=value:effect)")
-	
-	viper.BindPFlag("job-namespace", runJobCmd.Flags().Lookup("job-namespace"))
-	viper.BindPFlag("image", runJobCmd.Flags().Lookup("image"))
-	viper.BindPFlag("command", runJobCmd.Flags().Lookup("command"))
-	viper.BindPFlag("tolerations", runJobCmd.Flags().Lookup("tolerations"))
+	runJobCmd.Flags().BoolP("wait", "w", false, "Wait for the jobs to finish and report per-node results")
+	runJobCmd.Flags().Duration("timeout", 10*time.Minute, "Maximum time to wait for the jobs to finish (0 waits indefinitely)")
+	runJobCmd.Flags().BoolP("follow", "f", false, "Stream the logs of all job pods, each line prefixed with its node name")
+	runJobCmd.Flags().Bool("collect-logs", false, "Wait for the jobs to finish and print their logs grouped per node")
+	runJobCmd.Flags().String("log-dir", "", "Directory to write each node's job log to as <node>.log")
+
+	// Exec specific flags
+	execCmd.Flags().StringP("selector", "l", "", "Label selector for target pods (instead of a workload argument)")
+	execCmd.Flags().StringP("output", "o", "", "Output format: json|yaml|name|go-template=...|jsonpath=...")
+	execCmd.Flags().StringP("container", "c", "", "Container to run the command in (defaults to each pod's first container)")
+	execCmd.Flags().Int("parallelism", 10, "Maximum number of pods to run the command in concurrently")
+	execCmd.Flags().Duration("timeout", 0, "Maximum time the command may run in each pod (0 means no limit)")
+
+	// Runs specific flags
+	runsListCmd.Flags().StringP("output", "o", "", "Output format: json|yaml|go-template=...|jsonpath=...")
+	runsStatusCmd.Flags().StringP("output", "o", "", "Output format: json|yaml|name|go-template=...|jsonpath=...")
+	runsDeleteCmd.Flags().String("propagation", "background", "Propagation policy for deleting the jobs' pods: background or foreground")
+	runsCmd.AddCommand(runsListCmd)
+	runsCmd.AddCommand(runsStatusCmd)
+	runsCmd.AddCommand(runsDeleteCmd)
 
 	// Add commands to root
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(runJobCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(runsCmd)
+}
+
+// workloadFromArgs returns the target workload from either a kind/name argument or a label selector
+func workloadFromArgs(workloadArg, selector string) (k8s.WorkloadRef, error) {
+	switch {
+	case workloadArg != "" && selector != "":
+		return k8s.WorkloadRef{}, fmt.Errorf("a workload argument and --selector are mutually exclusive")
+	case selector != "":
+		return k8s.NewSelectorWorkloadRef(selector)
+	case workloadArg != "":
+		return k8s.ParseWorkloadRef(workloadArg)
+	default:
+		return k8s.WorkloadRef{}, fmt.Errorf("either a workload argument or --selector is required")
+	}
+}
+
+// newClientset creates a Kubernetes clientset from the global connection flags
+func newClientset() (*kubernetes.Clientset, error) {
+	clientset, err := k8s.NewClientWithOptions(clientOptions()).GetClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	return clientset, nil
+}
+
+// clientOptions returns the Kubernetes connection options set by the global flags
+func clientOptions() k8s.ClientOptions {
+	return k8s.ClientOptions{
+		Kubeconfig:        viper.GetString("kubeconfig"),
+		Context:           viper.GetString("context"),
+		Impersonate:       viper.GetString("as"),
+		ImpersonateGroups: viper.GetStringSlice("as-group"),
+		Timeout:           viper.GetDuration("request-timeout"),
+		QPS:               float32(viper.GetFloat64("qps")),
+		Burst:             viper.GetInt("burst"),
+		DisableInCluster:  !viper.GetBool("in-cluster"),
+	}
 }
 
 // parseTolerations parses tolerations from either JSON format or simple key=value:effect format
@@ -162,98 +428,451 @@ func parseTolerations(tolerationsStr string) ([]corev1.Toleration, error) {
 	return tolerations, nil
 }
 
-func listPodsAndNodes(deploymentName, namespace string) error {
-	client := k8s.NewClient("")
-	clientset, err := client.GetClient()
+func listPodsAndNodes(ctx context.Context, workload k8s.WorkloadRef, namespace string, podFilter podFilterOptions, format output.Format) error {
+	clientset, err := newClientset()
 	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %v", err)
+		return err
 	}
 
 	deploymentManager := k8s.NewDeploymentManager(clientset)
-	
-	pods, err := deploymentManager.GetPodsFromDeployment(deploymentName, namespace)
+	workloadResolver := k8s.NewWorkloadResolver(clientset)
+
+	pods, err := workloadResolver.GetPods(ctx, workload, namespace)
 	if err != nil {
 		return err
 	}
 
-	if len(pods) == 0 {
-		fmt.Printf("No pods found for deployment %s in namespace %s\n", deploymentName, namespace)
-		return nil
+	var out io.Writer = os.Stdout
+	if !format.IsTable() {
+		out = os.Stderr
+	}
+	pods, excluded, err := filterPods(ctx, out, workloadResolver, workload, namespace, podFilter, pods)
+	if err != nil {
+		return err
+	}
+
+	var revisions []k8s.RevisionGroup
+	if workload.Kind == k8s.KindDeployment {
+		replicaSets, err := deploymentManager.GetReplicaSets(ctx, workload.Name, namespace)
+		if err != nil {
+			return err
+		}
+		revisions = k8s.GroupByRevision(replicaSets, pods)
 	}
 
-	fmt.Printf("\nPods from deployment '%s' in namespace '%s':\n", deploymentName, namespace)
-	fmt.Println(strings.Repeat("-", 60))
-	fmt.Printf("%-40s %-20s\n", "Pod Name", "Node")
-	fmt.Println(strings.Repeat("-", 60))
+	if !format.IsTable() {
+		result := output.NewListResult(workload.String(), namespace, pods)
+		result.ExcludedPods = output.ExcludedPods(excluded)
+		result.Revisions = output.RevisionInfos(revisions)
+		return output.Print(os.Stdout, format, result)
+	}
 
-	for _, pod := range pods {
-		node := pod.Spec.NodeName
-		if node == "" {
-			node = "Pending"
+	if len(pods) == 0 {
+		if len(excluded) > 0 {
+			fmt.Printf("Every pod of %s in namespace %s was excluded by the pod filters\n", workload, namespace)
+			return nil
 		}
-		fmt.Printf("%-40s %-20s\n", pod.Name, node)
+		fmt.Printf("No pods found for %s in namespace %s\n", workload, namespace)
+		return nil
 	}
 
+	printPods(os.Stdout, workload, namespace, pods, format.Name == output.FormatWide)
+
 	nodes := deploymentManager.GetNodesFromPods(pods)
 
-	fmt.Printf("\nUnique nodes running pods from deployment '%s':\n", deploymentName)
+	fmt.Printf("\nUnique nodes running pods from %s:\n", workload)
 	fmt.Println(strings.Repeat("-", 30))
 	for _, node := range nodes {
 		fmt.Printf("  - %s\n", node)
 	}
 
+	if len(revisions) > 0 {
+		printRevisions(os.Stdout, workload, revisions)
+	}
 	return nil
 }
 
-func runJobOnNodes(deploymentName, jobName, namespace, jobNamespace, image string, command []string, tolerations []corev1.Toleration) error {
-	client := k8s.NewClient("")
-	clientset, err := client.GetClient()
+// printPods prints the target pods as a table, with phase and IPs when wide is set
+func printPods(out io.Writer, workload k8s.WorkloadRef, namespace string, pods []corev1.Pod, wide bool) {
+	fmt.Fprintf(out, "\nPods from %s in namespace '%s':\n", workload, namespace)
+	if wide {
+		fmt.Fprintln(out, strings.Repeat("-", 120))
+		fmt.Fprintf(out, "%-40s %-20s %-10s %-20s %-20s\n", "Pod Name", "Node", "Phase", "Pod IP", "Host IP")
+		fmt.Fprintln(out, strings.Repeat("-", 120))
+	} else {
+		fmt.Fprintln(out, strings.Repeat("-", 60))
+		fmt.Fprintf(out, "%-40s %-20s\n", "Pod Name", "Node")
+		fmt.Fprintln(out, strings.Repeat("-", 60))
+	}
+
+	for _, pod := range pods {
+		node := pod.Spec.NodeName
+		if node == "" {
+			node = "Pending"
+		}
+		if wide {
+			fmt.Fprintf(out, "%-40s %-20s %-10s %-20s %-20s\n", pod.Name, node, pod.Status.Phase, pod.Status.PodIP, pod.Status.HostIP)
+		} else {
+			fmt.Fprintf(out, "%-40s %-20s\n", pod.Name, node)
+		}
+	}
+}
+
+// runJobOptions holds the settings of a run-job invocation
+type runJobOptions struct {
+	workload           k8s.WorkloadRef
+	jobName            string
+	namespace          string
+	jobNamespace       string
+	image              string
+	command            []string
+	tolerations        []corev1.Toleration
+	placement          k8s.Placement
+	tolerateNodeTaints bool
+	taintFilter        k8s.TaintFilter
+	nodeFilter         k8s.NodeFilter
+	podFilter          podFilterOptions
+	revision           *k8s.RevisionSelector
+	settings           k8s.JobSettings
+	resources          corev1.ResourceRequirements
+	pullPolicy         corev1.PullPolicy
+	priorityClass      string
+	serviceAccount     string
+	pullSecrets        []corev1.LocalObjectReference
+	task               *k8s.Task
+	host               bool
+	showManifest       bool
+	dryRun             string
+	perPod             bool
+	runID              string
+	ifNotExists        bool
+	cleanupOnCancel    bool
+	parallelism        int
+	batch              k8s.BatchOptions
+	execMode           string
+	targetContainer    string
+	wait               bool
+	timeout            time.Duration
+	follow             bool
+	collectLogs        bool
+	logDir             string
+	output             output.Format
+	contexts           []string
+}
+
+func runJobOnNodes(ctx context.Context, opts runJobOptions) error {
+	if len(opts.contexts) > 0 {
+		return runJobOnClusters(ctx, opts)
+	}
+
+	// Keep stdout clean for machine-readable output by sending progress to stderr
+	var out io.Writer = os.Stdout
+	if !opts.output.IsTable() {
+		out = os.Stderr
+	}
+
+	clientset, err := newClientset()
 	if err != nil {
-		return fmt.Errorf("failed to create Kubernetes client: %v", err)
+		return err
 	}
 
-	deploymentManager := k8s.NewDeploymentManager(clientset)
+	result, err := runJob(ctx, opts, clientset, out)
+	if ctx.Err() != nil {
+		err = cancelRun(out, opts, k8s.NewRunManager(clientset))
+	}
+	if result != nil && !opts.output.IsTable() {
+		if printErr := output.Print(os.Stdout, opts.output, result); printErr != nil {
+			return printErr
+		}
+	}
+	return err
+}
+
+// runJob creates the jobs and optionally waits for them, writing progress to out.
+// The returned result is non-nil once the target pods have been resolved.
+func runJob(ctx context.Context, opts runJobOptions, clientset kubernetes.Interface, out io.Writer) (*output.RunJobResult, error) {
+	workloadResolver := k8s.NewWorkloadResolver(clientset)
 	jobManager := k8s.NewJobManager(clientset)
-	
-	pods, err := deploymentManager.GetPodsFromDeployment(deploymentName, namespace)
+	logManager := k8s.NewLogManager(clientset)
+
+	pods, err := workloadResolver.GetPods(ctx, opts.workload, opts.namespace)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	pods, excluded, err := filterPods(ctx, out, workloadResolver, opts.workload, opts.namespace, opts.podFilter, pods)
+	if err != nil {
+		return nil, err
+	}
+	if opts.revision != nil {
+		var revisionExcluded []k8s.PodExclusion
+		pods, revisionExcluded, err = selectRevision(ctx, out, k8s.NewDeploymentManager(clientset), opts, pods)
+		if err != nil {
+			return nil, err
+		}
+		excluded = append(excluded, revisionExcluded...)
+	}
+
+	result := output.NewRunJobResult(opts.workload.String(), opts.namespace, opts.jobNamespace, pods)
+	result.ExcludedPods = output.ExcludedPods(excluded)
+	if opts.execMode == execModeJob {
+		result.RunID = opts.runID
 	}
 
 	if len(pods) == 0 {
-		fmt.Printf("No pods found for deployment %s in namespace %s\n", deploymentName, namespace)
-		return nil
+		if len(excluded) > 0 {
+			fmt.Fprintf(out, "Every pod of %s in namespace %s was excluded by the pod filters\n", opts.workload, opts.namespace)
+			return result, nil
+		}
+		fmt.Fprintf(out, "No pods found for %s in namespace %s\n", opts.workload, opts.namespace)
+		return result, nil
 	}
 
-	nodes := deploymentManager.GetNodesFromPods(pods)
-	if len(nodes) == 0 {
-		fmt.Println("No nodes found with running pods")
-		return nil
+	if opts.output.Name == output.FormatWide {
+		printPods(out, opts.workload, opts.namespace, pods, true)
+	}
+
+	if opts.execMode == execModeEphemeral {
+		return result, runEphemeral(ctx, opts, clientset, out, pods, result)
 	}
 
-	fmt.Printf("\nCreating jobs on %d nodes in namespace %s...\n", len(nodes), jobNamespace)
-	
-	jobs, err := jobManager.CreateJobOnNodes(jobName, nodes, jobNamespace, image, command, tolerations)
+	targets := k8s.NodeTargets(pods)
+	if opts.perPod {
+		targets = k8s.PodTargets(pods)
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(out, "No nodes found with running pods")
+		return result, nil
+	}
+
+	if opts.placement == k8s.PlacementHostnameLabel || opts.tolerateNodeTaints || !opts.nodeFilter.IsEmpty() {
+		targets, err = resolveNodes(ctx, out, opts, k8s.NewNodeManager(clientset), targets, result)
+		if err != nil {
+			return result, err
+		}
+		result.Nodes = output.TargetNodeInfos(targets)
+		if len(targets) == 0 {
+			fmt.Fprintln(out, "\nEvery target node was excluded; nothing to do")
+			return result, nil
+		}
+	}
+
+	if opts.ifNotExists {
+		targets, err = skipActiveTargets(ctx, out, jobManager, opts, targets, result)
+		if err != nil {
+			return result, err
+		}
+		result.Nodes = output.TargetNodeInfos(targets)
+		if len(targets) == 0 {
+			fmt.Fprintln(out, "\nEvery target already has an active job; nothing to do")
+			return result, nil
+		}
+	}
+
+	if opts.dryRun == "" {
+		if opts.perPod {
+			fmt.Fprintf(out, "\nCreating jobs for %d pods in namespace %s...\n", len(targets), opts.jobNamespace)
+		} else {
+			fmt.Fprintf(out, "\nCreating jobs on %d nodes in namespace %s...\n", len(targets), opts.jobNamespace)
+		}
+	}
+
+	manifests, err := jobManager.BuildJobs(opts.jobName, targets, k8s.JobOptions{
+		Namespace:          opts.jobNamespace,
+		Image:              opts.image,
+		Command:            opts.command,
+		Tolerations:        opts.tolerations,
+		Workload:           opts.workload,
+		Task:               opts.task,
+		Host:               opts.host,
+		RunID:              opts.runID,
+		Version:            version,
+		Placement:          opts.placement,
+		Settings:           opts.settings,
+		Resources:          opts.resources,
+		ImagePullPolicy:    opts.pullPolicy,
+		PriorityClassName:  opts.priorityClass,
+		ServiceAccountName: opts.serviceAccount,
+		ImagePullSecrets:   opts.pullSecrets,
+	})
 	if err != nil {
-		log.Printf("Warning: %v", err)
+		return result, err
 	}
 
-	for _, job := range jobs {
-		fmt.Printf("Created job %s\n", job)
+	if opts.dryRun != "" {
+		return result, dryRunJobs(ctx, out, opts, jobManager, manifests, result)
 	}
 
-	if len(jobs) > 0 {
-		fmt.Printf("\nSuccessfully created %d jobs\n", len(jobs))
-	} else {
-		fmt.Println("\nNo jobs were created")
+	if opts.showManifest {
+		if err := printManifests(out, manifests); err != nil {
+			return result, err
+		}
+	}
+
+	if opts.batch.Enabled() {
+		return result, runJobInBatches(ctx, out, opts, jobManager, logManager, manifests, result)
 	}
 
+	created := submitJobs(ctx, out, jobManager, manifests, opts.parallelism, result)
+	jobs := k8s.CreatedJobs(created)
+	createFailed := len(manifests) - len(jobs)
+
+	if len(jobs) == 0 {
+		fmt.Fprintln(out, "\nNo jobs were created")
+		return result, failureError(createFailed, len(manifests), fmt.Errorf("failed to create all %d jobs", len(manifests)))
+	}
+	fmt.Fprintf(out, "\nSuccessfully created %d jobs\n", len(jobs))
+	if createFailed > 0 {
+		fmt.Fprintf(out, "Failed to create %d jobs\n", createFailed)
+	}
+
+	if opts.follow {
+		fmt.Fprintf(out, "\nStreaming logs from %d jobs...\n", len(jobs))
+		followJobLogs(ctx, out, logManager, jobs, opts.jobNamespace, opts.logDir, opts.timeout)
+	}
+
+	if !opts.wait && !opts.collectLogs {
+		return result, failureError(createFailed, len(manifests), fmt.Errorf("failed to create %d of %d jobs", createFailed, len(manifests)))
+	}
+
+	fmt.Fprintf(out, "\nWaiting for %d jobs to finish...\n", len(jobs))
+
+	results, err := jobManager.WaitForJobs(ctx, opts.jobNamespace, created, opts.timeout)
+	if err != nil {
+		return result, err
+	}
+	result.SetJobResults(results)
+
+	failed := createFailed + reportJobResults(ctx, out, opts, logManager, results, opts.jobNamespace)
+	return result, failureError(failed, len(manifests), fmt.Errorf("%d of %d targets did not succeed", failed, len(manifests)))
+}
+
+// skipActiveTargets drops the targets that still have an active job for the
+// same job name and workload, recording them as skipped
+func skipActiveTargets(ctx context.Context, out io.Writer, jobManager k8s.JobManagerInterface, opts runJobOptions, targets []k8s.JobTarget, result *output.RunJobResult) ([]k8s.JobTarget, error) {
+	active, err := jobManager.ActiveJobs(ctx, opts.jobNamespace, opts.jobName, opts.workload)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make([]k8s.JobTarget, 0, len(targets))
+	for _, target := range targets {
+		job, ok := active[target.Target()]
+		if !ok {
+			remaining = append(remaining, target)
+			continue
+		}
+		fmt.Fprintf(out, "Skipping %s: job %s is still active\n", target.Target(), job)
+		result.Skipped = append(result.Skipped, output.SkippedTarget{Target: target.Target(), Job: job})
+	}
+	return remaining, nil
+}
+
+// submitJobs creates the jobs, prints and records the outcome for every target
+// and returns the creations of the created jobs. Jobs skipped because the run was
+// interrupted are recorded but not printed.
+func submitJobs(ctx context.Context, out io.Writer, jobManager k8s.JobManagerInterface, manifests []*batchv1.Job, parallelism int, result *output.RunJobResult) []k8s.JobCreation {
+	creations := jobManager.SubmitJobs(ctx, manifests, parallelism)
+	for _, c := range creations {
+		switch {
+		case c.Created():
+			fmt.Fprintf(out, "Created job %s\n", c.Job)
+		case c.Category == k8s.ErrorCategoryCanceled:
+			// Not attempted; the interruption is reported once by cancelRun
+		default:
+			fmt.Fprintf(out, "Failed to create job %s on %s [%s]: %v\n", c.Job, c.Target(), c.Category, c.Err)
+		}
+	}
+	result.AddCreations(creations)
+	return k8s.SuccessfulCreations(creations)
+}
+
+// reportJobResults collects logs if requested, prints the per-target results
+// and returns how many of them did not succeed
+func reportJobResults(ctx context.Context, out io.Writer, opts runJobOptions, logManager k8s.LogManagerInterface, results []k8s.JobResult, namespace string) int {
+	if opts.collectLogs {
+		// Logs already written while following are not written again
+		logDir := opts.logDir
+		if opts.follow {
+			logDir = ""
+		}
+		collectJobLogs(ctx, out, logManager, results, namespace, logDir)
+	}
+
+	printJobResults(out, results)
+
+	failed := 0
+	for _, jobResult := range results {
+		if !jobResult.Succeeded() {
+			failed++
+		}
+	}
+	return failed
+}
+
+// printManifests writes the job manifests as a multi-document YAML stream
+func printManifests(out io.Writer, jobs []*batchv1.Job) error {
+	for _, job := range jobs {
+		data, err := yaml.Marshal(job)
+		if err != nil {
+			return fmt.Errorf("failed to encode job %s: %v", job.Name, err)
+		}
+		fmt.Fprintf(out, "---\n%s", data)
+	}
 	return nil
 }
 
+// printJobResults prints a per-node (or per-pod) summary table of job results
+func printJobResults(out io.Writer, results []k8s.JobResult) {
+	targetHeader := "Node"
+	for _, result := range results {
+		if result.TargetPod != "" {
+			targetHeader = "Node/Pod"
+			break
+		}
+	}
+
+	fmt.Fprintf(out, "\nJob results:\n")
+	fmt.Fprintln(out, strings.Repeat("-", 130))
+	fmt.Fprintf(out, "%-30s %-30s %-40s %-10s %-5s %-10s\n", targetHeader, "Job", "Pod", "Phase", "Exit", "Duration")
+	fmt.Fprintln(out, strings.Repeat("-", 130))
+
+	for _, result := range results {
+		pod := result.Pod
+		if pod == "" {
+			pod = "-"
+		}
+		exitCode := "-"
+		if result.ExitCode != nil {
+			exitCode = fmt.Sprintf("%d", *result.ExitCode)
+		}
+		duration := "-"
+		if result.Duration > 0 {
+			duration = result.Duration.Round(time.Second).String()
+		}
+		fmt.Fprintf(out, "%-30s %-30s %-40s %-10s %-5s %-10s\n", result.Target(), result.Job, pod, result.Phase, exitCode, duration)
+	}
+
+	for _, result := range results {
+		switch {
+		case result.Phase == k8s.JobPhaseUnschedulable:
+			fmt.Fprintf(out, "\nJob %s could not be scheduled on %s: %s\n", result.Job, result.Node, result.Message)
+		case result.Phase == k8s.JobPhaseUnknown && result.Message != "":
+			fmt.Fprintf(out, "\nJob %s could not be checked: %s\n", result.Job, result.Message)
+		}
+	}
+}
+
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// Interrupts cancel the context so that commands stop creating resources.
+	// A second interrupt terminates immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
-}
\ No newline at end of file
+}
@@ -1,9 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestParseTolerations(t *testing.T) {
@@ -116,9 +126,9 @@ func TestParseTolerations(t *testing.T) {
 			expectError: true,
 		},
 		{
-			name:     "empty string",
-			input:    "",
-			expected: []corev1.Toleration{},
+			name:        "empty string",
+			input:       "",
+			expected:    []corev1.Toleration{},
 			expectError: false,
 		},
 	}
@@ -126,7 +136,7 @@ func TestParseTolerations(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result, err := parseTolerations(tt.input)
-			
+
 			if tt.expectError {
 				if err == nil {
 					t.Errorf("Expected error but got none")
@@ -161,4 +171,234 @@ func TestParseTolerations(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestWorkloadFromArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		workloadArg string
+		selector    string
+		expected    k8s.WorkloadRef
+		expectError bool
+	}{
+		{
+			name:        "bare deployment name",
+			workloadArg: "nginx",
+			expected:    k8s.WorkloadRef{Kind: k8s.KindDeployment, Name: "nginx"},
+		},
+		{
+			name:        "kind/name argument",
+			workloadArg: "sts/redis",
+			expected:    k8s.WorkloadRef{Kind: k8s.KindStatefulSet, Name: "redis"},
+		},
+		{
+			name:     "label selector",
+			selector: "app=web,tier in (frontend)",
+			expected: k8s.WorkloadRef{Kind: k8s.KindSelector, Selector: "app=web,tier in (frontend)"},
+		},
+		{
+			name:        "both argument and selector",
+			workloadArg: "nginx",
+			selector:    "app=web",
+			expectError: true,
+		},
+		{
+			name:        "neither argument nor selector",
+			expectError: true,
+		},
+		{
+			name:        "invalid selector",
+			selector:    "app in (",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := workloadFromArgs(tt.workloadArg, tt.selector)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ref != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, ref)
+			}
+		})
+	}
+}
+
+func TestTargetContexts(t *testing.T) {
+	tests := []struct {
+		name        string
+		contexts    []string
+		allContexts bool
+		context     string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "single cluster",
+			expected: nil,
+		},
+		{
+			name:     "explicit contexts are deduplicated",
+			contexts: []string{"east", " west", "east", ""},
+			expected: []string{"east", "west"},
+		},
+		{
+			name:        "contexts and all-contexts",
+			contexts:    []string{"east"},
+			allContexts: true,
+			expectError: true,
+		},
+		{
+			name:        "contexts and context",
+			contexts:    []string{"east"},
+			context:     "west",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contexts, err := targetContexts(tt.contexts, tt.allContexts, tt.context)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(contexts) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, contexts)
+			}
+			for i := range tt.expected {
+				if contexts[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, contexts)
+				}
+			}
+		})
+	}
+}
+
+func TestContextOptions_LogDir(t *testing.T) {
+	logDir := t.TempDir()
+	opts := runJobOptions{jobNamespace: "jobs", logDir: logDir}
+
+	// Both clusters run the job on a node named node1
+	contexts := []string{"kind-east", "arn:aws:eks:us-east-1:123456789012:cluster/west"}
+	for _, kubeContext := range contexts {
+		clientset := fake.NewSimpleClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "task-abc-node1-xyz", Namespace: "jobs"}})
+		results := []k8s.JobResult{{Node: "node1", Job: "task-abc-node1", Pod: "task-abc-node1-xyz"}}
+		collectJobLogs(context.TODO(), io.Discard, k8s.NewLogManager(clientset), results, "jobs", contextOptions(opts, kubeContext).logDir)
+	}
+
+	for _, dir := range []string{"kind-east", "arn:aws:eks:us-east-1:123456789012:cluster_west"} {
+		if _, err := os.Stat(filepath.Join(logDir, dir, "node1.log")); err != nil {
+			t.Errorf("Expected a separate log for node1 in %s: %v", dir, err)
+		}
+	}
+
+	if got := contextOptions(runJobOptions{}, "kind-east").logDir; got != "" {
+		t.Errorf("Expected no log directory without --log-dir, got %q", got)
+	}
+}
+
+func TestPrintManifests(t *testing.T) {
+	jm := k8s.NewJobManager(fake.NewSimpleClientset())
+	jobs, err := jm.BuildJobs("inspect", []k8s.JobTarget{{Node: "node1"}, {Node: "node2"}}, k8s.JobOptions{
+		Namespace: "default",
+		Image:     "busybox",
+		Host:      true,
+	})
+	if err != nil {
+		t.Fatalf("BuildJobs() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printManifests(&buf, jobs); err != nil {
+		t.Fatalf("printManifests() error = %v", err)
+	}
+
+	manifest := buf.String()
+	if strings.Count(manifest, "---\n") != 2 {
+		t.Errorf("Expected 2 YAML documents, got:\n%s", manifest)
+	}
+	for _, want := range []string{"kind: Job", "apiVersion: batch/v1", "hostPID: true", "privileged: true", "mountPath: /host"} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("Expected manifest to contain %q", want)
+		}
+	}
+}
+
+func TestValidateExecMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        runJobOptions
+		expectError bool
+	}{
+		{name: "job mode", opts: runJobOptions{execMode: execModeJob, host: true}},
+		{name: "ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, targetContainer: "app"}},
+		{name: "unknown mode", opts: runJobOptions{execMode: "ssh"}, expectError: true},
+		{name: "target container in job mode", opts: runJobOptions{execMode: execModeJob, targetContainer: "app"}, expectError: true},
+		{name: "host in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, host: true}, expectError: true},
+		{name: "task file in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, task: &k8s.Task{}}, expectError: true},
+		{name: "manifest in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, showManifest: true}, expectError: true},
+		{name: "if-not-exists in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, ifNotExists: true}, expectError: true},
+		{name: "cleanup on cancel in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, cleanupOnCancel: true}, expectError: true},
+		{name: "placement in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, placement: k8s.PlacementNodeName}, expectError: true},
+		{name: "node taints in job mode", opts: runJobOptions{execMode: execModeJob, tolerateNodeTaints: true, taintFilter: k8s.TaintFilter{Deny: []string{"node.kubernetes.io/*"}}}},
+		{name: "node filters in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, nodeFilter: k8s.NodeFilter{ExcludeCordoned: true}}, expectError: true},
+		{name: "node taints in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, tolerateNodeTaints: true}, expectError: true},
+		{name: "taint filter without node taints", opts: runJobOptions{execMode: execModeJob, taintFilter: k8s.TaintFilter{Allow: []string{"dedicated"}}}, expectError: true},
+		{name: "pod settings in ephemeral mode", opts: runJobOptions{execMode: execModeEphemeral, serviceAccount: "inspector"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateExecMode(tt.opts)
+			if (err != nil) != tt.expectError {
+				t.Errorf("validateExecMode() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestSkipActiveTargets(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	jm := k8s.NewJobManager(clientset)
+	opts := runJobOptions{
+		workload:     k8s.WorkloadRef{Kind: k8s.KindDeployment, Name: "web"},
+		jobName:      "inspect",
+		jobNamespace: "default",
+		runID:        "previous",
+	}
+
+	// A job from a previous run is still running on node1
+	jobs, err := jm.BuildJobs(opts.jobName, []k8s.JobTarget{{Node: "node1"}}, k8s.JobOptions{Namespace: "default", Workload: opts.workload, RunID: opts.runID})
+	if err != nil {
+		t.Fatalf("BuildJobs() error = %v", err)
+	}
+	if creations := jm.SubmitJobs(context.TODO(), jobs, 1); !creations[0].Created() {
+		t.Fatalf("Failed to create job: %v", creations[0].Err)
+	}
+
+	result := output.NewRunJobResult(opts.workload.String(), "default", "default", nil)
+	targets, err := skipActiveTargets(context.TODO(), io.Discard, jm, opts, []k8s.JobTarget{{Node: "node1"}, {Node: "node2"}}, result)
+	if err != nil {
+		t.Fatalf("skipActiveTargets() error = %v", err)
+	}
+	if len(targets) != 1 || targets[0].Node != "node2" {
+		t.Errorf("Expected only node2 to remain, got %v", targets)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != (output.SkippedTarget{Target: "node1", Job: jobs[0].Name}) {
+		t.Errorf("Unexpected skipped targets %v", result.Skipped)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+)
+
+// targetContexts returns the kubeconfig contexts to run in, or nil for a single-cluster run
+func targetContexts(contexts []string, allContexts bool, context string) ([]string, error) {
+	if len(contexts) > 0 && allContexts {
+		return nil, fmt.Errorf("--contexts and --all-contexts are mutually exclusive")
+	}
+	if (len(contexts) > 0 || allContexts) && context != "" {
+		return nil, fmt.Errorf("--context cannot be combined with --contexts or --all-contexts")
+	}
+
+	if allContexts {
+		all, err := k8s.KubeconfigContexts(viper.GetString("kubeconfig"))
+		if err != nil {
+			return nil, err
+		}
+		if len(all) == 0 {
+			return nil, fmt.Errorf("no contexts found in kubeconfig")
+		}
+		return all, nil
+	}
+
+	seen := make(map[string]bool)
+	var unique []string
+	for _, context := range contexts {
+		context = strings.TrimSpace(context)
+		if context == "" || seen[context] {
+			continue
+		}
+		seen[context] = true
+		unique = append(unique, context)
+	}
+	return unique, nil
+}
+
+// runJobOnClusters runs the job in every context concurrently. Progress lines are
+// prefixed with the context name, and a failure in one cluster does not stop the others.
+func runJobOnClusters(ctx context.Context, opts runJobOptions) error {
+	var out io.Writer = os.Stdout
+	if !opts.output.IsTable() {
+		out = os.Stderr
+	}
+
+	fmt.Fprintf(out, "Running in %d clusters: %s\n", len(opts.contexts), strings.Join(opts.contexts, ", "))
+
+	clusters := make([]output.ClusterRunJobResult, len(opts.contexts))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i, kubeContext := range opts.contexts {
+		wg.Add(1)
+		go func(i int, kubeContext string) {
+			defer wg.Done()
+
+			pw := k8s.NewPrefixWriter(out, &mu, fmt.Sprintf("[%s] ", kubeContext))
+			defer pw.Flush()
+
+			clusters[i] = runJobInContext(ctx, opts, kubeContext, pw)
+		}(i, kubeContext)
+	}
+	wg.Wait()
+
+	result := output.NewMultiClusterRunJobResult()
+	result.Clusters = clusters
+
+	if opts.output.IsTable() {
+		printClusterSummary(out, clusters)
+	} else if err := output.Print(os.Stdout, opts.output, result); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return canceledError(fmt.Errorf("run-job canceled: %v", err))
+	}
+
+	failed := 0
+	for _, cluster := range clusters {
+		if cluster.Error != "" {
+			failed++
+		}
+	}
+	return failureError(failed, len(clusters), fmt.Errorf("%d of %d clusters failed", failed, len(clusters)))
+}
+
+// runJobInContext runs the job against a single kubeconfig context
+func runJobInContext(ctx context.Context, opts runJobOptions, kubeContext string, out io.Writer) output.ClusterRunJobResult {
+	cluster := output.ClusterRunJobResult{Context: kubeContext}
+
+	options := clientOptions()
+	options.Context = kubeContext
+	clientset, err := k8s.NewClientWithOptions(options).GetClient()
+	if err != nil {
+		cluster.Error = fmt.Sprintf("failed to create Kubernetes client: %v", err)
+		fmt.Fprintf(out, "Error: %s\n", cluster.Error)
+		return cluster
+	}
+
+	opts = contextOptions(opts, kubeContext)
+	result, err := runJob(ctx, opts, clientset, out)
+	if ctx.Err() != nil {
+		err = cancelRun(out, opts, k8s.NewRunManager(clientset))
+	}
+	cluster.Result = result
+	if err != nil {
+		cluster.Error = err.Error()
+		fmt.Fprintf(out, "Error: %s\n", cluster.Error)
+	}
+	return cluster
+}
+
+// contextOptions returns the options for the run in kubeContext. Logs are written
+// to a directory per context, since node names can repeat across clusters.
+func contextOptions(opts runJobOptions, kubeContext string) runJobOptions {
+	if opts.logDir != "" {
+		opts.logDir = filepath.Join(opts.logDir, strings.ReplaceAll(kubeContext, "/", "_"))
+	}
+	return opts
+}
+
+// printClusterSummary prints the aggregated run-job report grouped by cluster
+func printClusterSummary(out io.Writer, clusters []output.ClusterRunJobResult) {
+	fmt.Fprintf(out, "\nSummary by cluster:\n")
+
+	for _, cluster := range clusters {
+		fmt.Fprintf(out, "\n==> %s <==\n", cluster.Context)
+		if cluster.Result != nil {
+			fmt.Fprintf(out, "Target: %s, nodes: %d, jobs created: %d\n", cluster.Result.Target, len(cluster.Result.Nodes), len(cluster.Result.Jobs))
+			for _, job := range cluster.Result.Jobs {
+				phase := job.Phase
+				if phase == "" {
+					phase = "Created"
+				}
+				fmt.Fprintf(out, "  %-40s %-30s %-10s\n", job.Name, k8s.TargetName(job.Node, job.TargetPod), phase)
+			}
+		}
+		if cluster.Error != "" {
+			fmt.Fprintf(out, "Error: %s\n", cluster.Error)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+)
+
+// resolveNodes looks up the target nodes, drops the targets on nodes excluded
+// by the node filters and sets the hostname labels and, with
+// --tolerate-node-taints, tolerations for the taints of the remaining nodes
+func resolveNodes(ctx context.Context, out io.Writer, opts runJobOptions, nodeManager k8s.NodeManagerInterface, targets []k8s.JobTarget, result *output.RunJobResult) ([]k8s.JobTarget, error) {
+	nodes, err := nodeManager.GetNodes(ctx, k8s.TargetNodes(targets))
+	if err != nil {
+		if opts.tolerateNodeTaints || !opts.nodeFilter.IsEmpty() {
+			return nil, err
+		}
+		log.Printf("Warning: %v; assuming hostname labels equal node names", err)
+		return targets, nil
+	}
+
+	if !opts.nodeFilter.IsEmpty() {
+		var exclusions []k8s.NodeExclusion
+		targets, exclusions = k8s.FilterTargets(targets, nodes, opts.nodeFilter)
+		for _, exclusion := range exclusions {
+			fmt.Fprintf(out, "Excluding node %s: %s\n", exclusion.Node, exclusion.Reason)
+		}
+		result.AddExclusions(exclusions)
+	}
+
+	if opts.placement == k8s.PlacementHostnameLabel {
+		k8s.SetHostnames(targets, nodes)
+	}
+
+	if opts.tolerateNodeTaints {
+		decisions := k8s.TolerateNodeTaints(targets, nodes, opts.tolerations, opts.taintFilter)
+		result.AddTaintDecisions(decisions)
+
+		added := 0
+		for _, decision := range decisions {
+			if decision.Toleration != nil {
+				added++
+			}
+		}
+		if opts.dryRun == "" {
+			fmt.Fprintf(out, "Tolerating %d of %d node taints\n", added, len(decisions))
+		}
+	}
+	return targets, nil
+}
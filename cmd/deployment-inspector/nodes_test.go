@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestResolveNodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           runJobOptions
+		listErr        bool
+		wantErr        bool
+		wantHostname   string
+		wantTaints     int
+		wantToleration bool
+		wantTargets    int
+		wantExcluded   []output.ExcludedNode
+	}{
+		{name: "hostname label", opts: runJobOptions{placement: k8s.PlacementHostnameLabel}, wantHostname: "ip-10-0-0-1", wantTargets: 2},
+		{name: "node taints", opts: runJobOptions{placement: k8s.PlacementNodeName, tolerateNodeTaints: true}, wantTaints: 1, wantToleration: true, wantTargets: 2},
+		{name: "denied node taints", opts: runJobOptions{tolerateNodeTaints: true, taintFilter: k8s.TaintFilter{Deny: []string{"gpu"}}}, wantTaints: 1, wantTargets: 2},
+		{
+			name:         "exclude cordoned nodes",
+			opts:         runJobOptions{placement: k8s.PlacementHostnameLabel, nodeFilter: k8s.NodeFilter{ExcludeCordoned: true}},
+			wantHostname: "ip-10-0-0-1",
+			wantTargets:  1,
+			wantExcluded: []output.ExcludedNode{{Node: "node2", Reason: "node is cordoned"}},
+		},
+		{name: "node lookup fails for hostname label", opts: runJobOptions{placement: k8s.PlacementHostnameLabel}, listErr: true, wantTargets: 2},
+		{name: "node lookup fails for node taints", opts: runJobOptions{tolerateNodeTaints: true}, listErr: true, wantErr: true},
+		{name: "node lookup fails for node filters", opts: runJobOptions{nodeFilter: k8s.NodeFilter{ReadyOnly: true}}, listErr: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				&corev1.Node{
+					ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{corev1.LabelHostname: "ip-10-0-0-1"}},
+					Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: "gpu", Effect: corev1.TaintEffectNoSchedule}}},
+				},
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			)
+			if tt.listErr {
+				clientset.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, fmt.Errorf("nodes is forbidden")
+				})
+			}
+
+			result := &output.RunJobResult{}
+			var out bytes.Buffer
+			targets, err := resolveNodes(context.TODO(), &out, tt.opts, k8s.NewNodeManager(clientset), []k8s.JobTarget{{Node: "node1"}, {Node: "node2"}}, result)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveNodes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(targets) != tt.wantTargets {
+				t.Fatalf("Expected %d targets, got %+v", tt.wantTargets, targets)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(result.ExcludedNodes, tt.wantExcluded) {
+				t.Errorf("Expected excluded nodes %+v, got %+v", tt.wantExcluded, result.ExcludedNodes)
+			}
+			if targets[0].Hostname != tt.wantHostname {
+				t.Errorf("Expected hostname %q, got %q", tt.wantHostname, targets[0].Hostname)
+			}
+			if len(result.NodeTaints) != tt.wantTaints {
+				t.Errorf("Expected %d node taints, got %+v", tt.wantTaints, result.NodeTaints)
+			}
+			if (len(targets[0].Tolerations) > 0) != tt.wantToleration {
+				t.Errorf("Expected toleration %v, got %+v", tt.wantToleration, targets[0].Tolerations)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// podFilterOptions select the target pods of list and run-job. The current
+// revision is looked up per cluster when the pods are filtered.
+type podFilterOptions struct {
+	filter              k8s.PodFilter
+	currentRevisionOnly bool
+}
+
+// isEmpty reports whether every pod is kept
+func (o podFilterOptions) isEmpty() bool {
+	return o.filter.IsEmpty() && !o.currentRevisionOnly
+}
+
+// addPodFilterFlags adds the pod filter flags to cmd
+func addPodFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("pod-phase", nil, "Only target pods in these phases (comma-separated, e.g. Running)")
+	cmd.Flags().Bool("ready-only", false, "Only target pods whose Ready condition is True")
+	cmd.Flags().Bool("exclude-terminating", false, "Do not target pods that are being deleted")
+	cmd.Flags().Bool("current-revision-only", false, "Only target pods of the newest revision of the workload (the newest ReplicaSet of a deployment)")
+	cmd.Flags().String("pod-selector", "", "Only target pods that also match this label selector")
+}
+
+// parsePodFilterFlags reads the pod filter flags
+func parsePodFilterFlags() (podFilterOptions, error) {
+	phases, err := k8s.ParsePodPhases(viper.GetStringSlice("pod-phase"))
+	if err != nil {
+		return podFilterOptions{}, err
+	}
+	opts := podFilterOptions{
+		filter: k8s.PodFilter{
+			Phases:             phases,
+			ReadyOnly:          viper.GetBool("ready-only"),
+			ExcludeTerminating: viper.GetBool("exclude-terminating"),
+		},
+		currentRevisionOnly: viper.GetBool("current-revision-only"),
+	}
+	if value := viper.GetString("pod-selector"); value != "" {
+		opts.filter.Selector, err = labels.Parse(value)
+		if err != nil {
+			return podFilterOptions{}, fmt.Errorf("invalid pod selector %q: %v", value, err)
+		}
+	}
+	return opts, nil
+}
+
+// filterPods drops the pods excluded by the pod filters and prints why
+func filterPods(ctx context.Context, out io.Writer, resolver k8s.WorkloadResolverInterface, workload k8s.WorkloadRef, namespace string, opts podFilterOptions, pods []corev1.Pod) ([]corev1.Pod, []k8s.PodExclusion, error) {
+	if opts.isEmpty() {
+		return pods, nil, nil
+	}
+
+	filter := opts.filter
+	if opts.currentRevisionOnly {
+		revision, err := resolver.CurrentRevision(ctx, workload, namespace)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter.Revision = revision
+	}
+
+	pods, exclusions := k8s.FilterPods(pods, filter)
+	for _, exclusion := range exclusions {
+		fmt.Fprintf(out, "Excluding pod %s: %s\n", exclusion.Pod, exclusion.Reason)
+	}
+	return pods, exclusions, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFilterPods(t *testing.T) {
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"},
+		Status:     appsv1.StatefulSetStatus{UpdateRevision: "redis-2"},
+	}
+	newPod := func(name, revision string, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{appsv1.ControllerRevisionHashLabelKey: revision}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	pods := []corev1.Pod{
+		newPod("redis-0", "redis-2", corev1.PodRunning),
+		newPod("redis-1", "redis-1", corev1.PodRunning),
+		newPod("redis-2", "redis-2", corev1.PodSucceeded),
+	}
+	workload := k8s.WorkloadRef{Kind: k8s.KindStatefulSet, Name: "redis"}
+
+	tests := []struct {
+		name     string
+		opts     podFilterOptions
+		wantPods []string
+	}{
+		{name: "no filters", wantPods: []string{"redis-0", "redis-1", "redis-2"}},
+		{name: "phase", opts: podFilterOptions{filter: k8s.PodFilter{Phases: []corev1.PodPhase{corev1.PodRunning}}}, wantPods: []string{"redis-0", "redis-1"}},
+		{name: "current revision", opts: podFilterOptions{currentRevisionOnly: true}, wantPods: []string{"redis-0", "redis-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := k8s.NewWorkloadResolver(fake.NewSimpleClientset(sts))
+			var out bytes.Buffer
+			kept, exclusions, err := filterPods(context.TODO(), &out, resolver, workload, "default", tt.opts, pods)
+			if err != nil {
+				t.Fatalf("filterPods() error = %v", err)
+			}
+			var names []string
+			for _, pod := range kept {
+				names = append(names, pod.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantPods) {
+				t.Errorf("Expected pods %v, got %v", tt.wantPods, names)
+			}
+			if len(exclusions) != len(pods)-len(kept) {
+				t.Errorf("Expected %d exclusions, got %+v", len(pods)-len(kept), exclusions)
+			}
+		})
+	}
+
+	// The current revision of a raw selector cannot be determined
+	resolver := k8s.NewWorkloadResolver(fake.NewSimpleClientset())
+	selector := k8s.WorkloadRef{Kind: k8s.KindSelector, Selector: "app=redis"}
+	if _, _, err := filterPods(context.TODO(), &bytes.Buffer{}, resolver, selector, "default", podFilterOptions{currentRevisionOnly: true}, pods); err == nil {
+		t.Error("Expected an error for --current-revision-only with a selector")
+	}
+}
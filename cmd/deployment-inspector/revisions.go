@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// validateRevision checks that --revision targets a deployment and is not
+// combined with --current-revision-only
+func validateRevision(opts runJobOptions) error {
+	if opts.revision == nil {
+		return nil
+	}
+	if opts.workload.Kind != k8s.KindDeployment {
+		return fmt.Errorf("--revision requires a deployment, got %s", opts.workload)
+	}
+	if opts.podFilter.currentRevisionOnly {
+		return fmt.Errorf("--revision cannot be combined with --current-revision-only")
+	}
+	return nil
+}
+
+// selectRevision drops the pods of deployment revisions not selected by
+// --revision and prints why
+func selectRevision(ctx context.Context, out io.Writer, deploymentManager k8s.DeploymentManagerInterface, opts runJobOptions, pods []corev1.Pod) ([]corev1.Pod, []k8s.PodExclusion, error) {
+	replicaSets, err := deploymentManager.GetReplicaSets(ctx, opts.workload.Name, opts.namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pods, exclusions := k8s.SelectRevisionPods(replicaSets, pods, *opts.revision)
+	for _, exclusion := range exclusions {
+		fmt.Fprintf(out, "Excluding pod %s: %s\n", exclusion.Pod, exclusion.Reason)
+	}
+	return pods, exclusions, nil
+}
+
+// printRevisions prints the pods of a deployment grouped by ReplicaSet, newest revision first
+func printRevisions(out io.Writer, workload k8s.WorkloadRef, revisions []k8s.RevisionGroup) {
+	fmt.Fprintf(out, "\nRevisions of %s:\n", workload)
+	fmt.Fprintln(out, strings.Repeat("-", 120))
+	fmt.Fprintf(out, "%-10s %-35s %-6s %-30s %s\n", "Revision", "ReplicaSet", "Pods", "Images", "Nodes")
+	fmt.Fprintln(out, strings.Repeat("-", 120))
+
+	for _, revision := range revisions {
+		name := fmt.Sprintf("%d", revision.Revision)
+		if revision.Current {
+			name += " (new)"
+		}
+		nodes := strings.Join(revision.Nodes, ", ")
+		if nodes == "" {
+			nodes = "<none>"
+		}
+		fmt.Fprintf(out, "%-10s %-35s %-6d %-30s %s\n", name, revision.ReplicaSet, len(revision.Pods), strings.Join(revision.Images, ", "), nodes)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestValidateRevision(t *testing.T) {
+	deployment := k8s.WorkloadRef{Kind: k8s.KindDeployment, Name: "web"}
+	revision := &k8s.RevisionSelector{Value: k8s.RevisionOld}
+
+	tests := []struct {
+		name        string
+		opts        runJobOptions
+		expectError bool
+	}{
+		{name: "no revision", opts: runJobOptions{workload: k8s.WorkloadRef{Kind: k8s.KindDaemonSet, Name: "fluentd"}}},
+		{name: "deployment", opts: runJobOptions{workload: deployment, revision: revision}},
+		{name: "statefulset", opts: runJobOptions{workload: k8s.WorkloadRef{Kind: k8s.KindStatefulSet, Name: "redis"}, revision: revision}, expectError: true},
+		{name: "with current revision only", opts: runJobOptions{workload: deployment, revision: revision, podFilter: podFilterOptions{currentRevisionOnly: true}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateRevision(tt.opts); (err != nil) != tt.expectError {
+				t.Errorf("validateRevision() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestSelectRevision(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "deploy-uid"},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	newReplicaSet := func(name, revision string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			UID:             types.UID("uid-" + name),
+			Labels:          map[string]string{"app": "web"},
+			Annotations:     map[string]string{k8s.RevisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+		}}
+	}
+	oldRS, newRS := newReplicaSet("web-old", "1"), newReplicaSet("web-new", "2")
+	newPod := func(name string, rs *appsv1.ReplicaSet) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(rs, appsv1.SchemeGroupVersion.WithKind("ReplicaSet"))},
+		}}
+	}
+	pods := []corev1.Pod{newPod("web-old-1", oldRS), newPod("web-new-1", newRS)}
+
+	opts := runJobOptions{
+		workload:  k8s.WorkloadRef{Kind: k8s.KindDeployment, Name: "web"},
+		namespace: "default",
+		revision:  &k8s.RevisionSelector{Value: k8s.RevisionOld},
+	}
+	var out bytes.Buffer
+	kept, exclusions, err := selectRevision(context.TODO(), &out, k8s.NewDeploymentManager(fake.NewSimpleClientset(deployment, oldRS, newRS)), opts, pods)
+	if err != nil {
+		t.Fatalf("selectRevision() error = %v", err)
+	}
+	if len(kept) != 1 || kept[0].Name != "web-old-1" {
+		t.Errorf("Expected only web-old-1, got %v", kept)
+	}
+	if len(exclusions) != 1 || !strings.Contains(out.String(), "Excluding pod web-new-1: not revision old") {
+		t.Errorf("Unexpected exclusions %+v, output %q", exclusions, out.String())
+	}
+}
+
+func TestPrintRevisions(t *testing.T) {
+	var out bytes.Buffer
+	printRevisions(&out, k8s.WorkloadRef{Kind: k8s.KindDeployment, Name: "web"}, []k8s.RevisionGroup{
+		{ReplicaSet: "web-new", Revision: 3, Current: true, Images: []string{"nginx:1.25"}},
+		{ReplicaSet: "web-old", Revision: 2, Images: []string{"nginx:1.24"}, Pods: []corev1.Pod{{}, {}}, Nodes: []string{"node1", "node2"}},
+	})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 lines, got %q", out.String())
+	}
+	if fields := strings.Fields(lines[4]); fields[0] != "3" || fields[1] != "(new)" || fields[3] != "0" || fields[5] != "<none>" {
+		t.Errorf("Unexpected row for the new revision: %q", lines[4])
+	}
+	if !strings.HasSuffix(lines[5], "node1, node2") {
+		t.Errorf("Unexpected row for the old revision: %q", lines[5])
+	}
+}
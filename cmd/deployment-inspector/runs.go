@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	"github.com/takutakahashi/deployment-inspector/pkg/output"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+var (
+	runsCmd = &cobra.Command{
+		Use:   "runs",
+		Short: "Inspect and clean up the jobs of earlier run-job invocations",
+		Long: `Inspect and clean up the jobs of earlier run-job invocations.
+
+Jobs are found by the deployment-inspector/run-id label in the namespace given
+with --namespace, which is the job namespace of the run.`,
+	}
+
+	runsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List runs with jobs in the namespace",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := output.ParseFormat(viper.GetString("output"))
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return listRuns(cmd.Context(), viper.GetString("namespace"), format)
+		},
+	}
+
+	runsStatusCmd = &cobra.Command{
+		Use:   "status <run-id>",
+		Short: "Show the per-node progress of a run",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := output.ParseFormat(viper.GetString("output"))
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return showRunStatus(cmd.Context(), viper.GetString("namespace"), args[0], format)
+		},
+	}
+
+	runsDeleteCmd = &cobra.Command{
+		Use:   "delete <run-id>",
+		Short: "Delete the jobs of a run together with their pods",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			propagation, err := parsePropagation(viper.GetString("propagation"))
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return deleteRun(cmd.Context(), viper.GetString("namespace"), args[0], propagation)
+		},
+	}
+)
+
+func listRuns(ctx context.Context, namespace string, format output.Format) error {
+	clientset, err := newClientset()
+	if err != nil {
+		return err
+	}
+
+	runs, err := k8s.NewRunManager(clientset).ListRuns(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	if !format.IsTable() {
+		return output.Print(os.Stdout, format, output.NewRunListResult(namespace, runs))
+	}
+
+	if len(runs) == 0 {
+		fmt.Printf("No runs found in namespace %s\n", namespace)
+		return nil
+	}
+	printRuns(os.Stdout, runs, time.Now())
+	return nil
+}
+
+func showRunStatus(ctx context.Context, namespace, runID string, format output.Format) error {
+	clientset, err := newClientset()
+	if err != nil {
+		return err
+	}
+
+	results, err := k8s.NewRunManager(clientset).RunStatus(ctx, namespace, runID)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no jobs found for run %s in namespace %s", runID, namespace)
+	}
+
+	if !format.IsTable() {
+		return output.Print(os.Stdout, format, output.NewRunStatusResult(namespace, runID, results))
+	}
+
+	fmt.Printf("Run %s in namespace %s: %s\n", runID, namespace, runProgress(results))
+	printJobResults(os.Stdout, results)
+	return nil
+}
+
+func deleteRun(ctx context.Context, namespace, runID string, propagation metav1.DeletionPropagation) error {
+	clientset, err := newClientset()
+	if err != nil {
+		return err
+	}
+
+	deleted, err := k8s.NewRunManager(clientset).DeleteRun(ctx, namespace, runID, propagation)
+	for _, job := range deleted {
+		fmt.Printf("Deleted job %s\n", job)
+	}
+	if err != nil {
+		return err
+	}
+	if len(deleted) == 0 {
+		return fmt.Errorf("no jobs found for run %s in namespace %s", runID, namespace)
+	}
+
+	fmt.Printf("\nDeleted %d jobs of run %s\n", len(deleted), runID)
+	return nil
+}
+
+// parsePropagation parses the --propagation flag of runs delete
+func parsePropagation(value string) (metav1.DeletionPropagation, error) {
+	switch strings.ToLower(value) {
+	case "background":
+		return metav1.DeletePropagationBackground, nil
+	case "foreground":
+		return metav1.DeletePropagationForeground, nil
+	default:
+		return "", fmt.Errorf("invalid propagation policy %q (expected background or foreground)", value)
+	}
+}
+
+// runProgress summarizes the phases of the jobs of a run, e.g. "3 jobs: 2 Succeeded, 1 Running"
+func runProgress(results []k8s.JobResult) string {
+	counts := make(map[k8s.JobPhase]int)
+	var phases []k8s.JobPhase
+	for _, result := range results {
+		if counts[result.Phase] == 0 {
+			phases = append(phases, result.Phase)
+		}
+		counts[result.Phase]++
+	}
+
+	parts := make([]string, 0, len(phases))
+	for _, phase := range phases {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[phase], phase))
+	}
+	return fmt.Sprintf("%d jobs: %s", len(results), strings.Join(parts, ", "))
+}
+
+// printRuns prints a summary table of runs
+func printRuns(out io.Writer, runs []k8s.RunSummary, now time.Time) {
+	fmt.Fprintln(out, strings.Repeat("-", 120))
+	fmt.Fprintf(out, "%-22s %-25s %-30s %-6s %-7s %-10s %-7s %-8s\n", "Run ID", "Task", "Target", "Jobs", "Active", "Succeeded", "Failed", "Age")
+	fmt.Fprintln(out, strings.Repeat("-", 120))
+
+	for _, run := range runs {
+		fmt.Fprintf(out, "%-22s %-25s %-30s %-6d %-7d %-10d %-7d %-8s\n",
+			run.RunID, run.Task, run.Target, run.Jobs, run.Active, run.Succeeded, run.Failed, duration.HumanDuration(now.Sub(run.Created)))
+	}
+}
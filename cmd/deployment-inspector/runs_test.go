@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePropagation(t *testing.T) {
+	tests := []struct {
+		value       string
+		expected    metav1.DeletionPropagation
+		expectError bool
+	}{
+		{value: "background", expected: metav1.DeletePropagationBackground},
+		{value: "Foreground", expected: metav1.DeletePropagationForeground},
+		{value: "orphan", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			propagation, err := parsePropagation(tt.value)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("parsePropagation() error = %v, expectError %v", err, tt.expectError)
+			}
+			if propagation != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, propagation)
+			}
+		})
+	}
+}
+
+func TestRunProgress(t *testing.T) {
+	progress := runProgress([]k8s.JobResult{
+		{Phase: k8s.JobPhaseSucceeded},
+		{Phase: k8s.JobPhaseRunning},
+		{Phase: k8s.JobPhaseSucceeded},
+	})
+	if progress != "3 jobs: 2 Succeeded, 1 Running" {
+		t.Errorf("Unexpected progress %q", progress)
+	}
+}
+
+func TestPrintRuns(t *testing.T) {
+	now := time.Now()
+	var buf bytes.Buffer
+	printRuns(&buf, []k8s.RunSummary{
+		{RunID: "abc", Task: "inspect", Target: "deployment.web", Created: now.Add(-90 * time.Minute), Jobs: 3, Active: 1, Succeeded: 2},
+	}, now)
+
+	for _, want := range []string{"Run ID", "abc", "inspect", "deployment.web", "90m"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// BatchOptions configures rolling execution of jobs in batches
+type BatchOptions struct {
+	// Canary is the number of targets run on their own first; all of them must succeed
+	Canary int
+	// BatchSize is the number of targets per batch after the canary; zero runs the rest at once
+	BatchSize int
+	// MaxFailures is the number of failed targets tolerated before the rollout aborts
+	MaxFailures FailureThreshold
+}
+
+// Enabled reports whether jobs should be run in batches
+func (o BatchOptions) Enabled() bool {
+	return o.Canary > 0 || o.BatchSize > 0
+}
+
+// FailureThreshold is a failure limit given as an absolute count or a percentage of targets
+type FailureThreshold struct {
+	Value   int
+	Percent bool
+}
+
+// ParseFailureThreshold parses a count such as "3" or a percentage such as "10%"
+func ParseFailureThreshold(s string) (FailureThreshold, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return FailureThreshold{}, nil
+	}
+
+	percent := strings.HasSuffix(s, "%")
+	value, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+	if err != nil || value < 0 {
+		return FailureThreshold{}, fmt.Errorf("invalid failure threshold %q (expected a count such as 3 or a percentage such as 10%%)", s)
+	}
+	if percent && value > 100 {
+		return FailureThreshold{}, fmt.Errorf("invalid failure threshold %q (percentage must be at most 100%%)", s)
+	}
+
+	return FailureThreshold{Value: value, Percent: percent}, nil
+}
+
+// Limit returns the number of failures tolerated out of total targets
+func (t FailureThreshold) Limit(total int) int {
+	if t.Percent {
+		return total * t.Value / 100
+	}
+	return t.Value
+}
+
+// String returns the threshold as it was given
+func (t FailureThreshold) String() string {
+	if t.Percent {
+		return fmt.Sprintf("%d%%", t.Value)
+	}
+	return strconv.Itoa(t.Value)
+}
+
+// PlanBatches splits jobs into the canary batch followed by batches of BatchSize.
+// The order of jobs is preserved.
+func PlanBatches(jobs []*batchv1.Job, opts BatchOptions) [][]*batchv1.Job {
+	var batches [][]*batchv1.Job
+
+	rest := jobs
+	if opts.Canary > 0 && len(rest) > 0 {
+		n := opts.Canary
+		if n > len(rest) {
+			n = len(rest)
+		}
+		batches = append(batches, rest[:n])
+		rest = rest[n:]
+	}
+
+	size := opts.BatchSize
+	if size <= 0 {
+		size = len(rest)
+	}
+	for len(rest) > 0 {
+		n := size
+		if n > len(rest) {
+			n = len(rest)
+		}
+		batches = append(batches, rest[:n])
+		rest = rest[n:]
+	}
+
+	return batches
+}
@@ -0,0 +1,85 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseFailureThreshold(t *testing.T) {
+	tests := []struct {
+		input       string
+		total       int
+		wantLimit   int
+		expectError bool
+	}{
+		{input: "", total: 10, wantLimit: 0},
+		{input: "3", total: 10, wantLimit: 3},
+		{input: "10%", total: 25, wantLimit: 2},
+		{input: "100%", total: 7, wantLimit: 7},
+		{input: "-1", expectError: true},
+		{input: "150%", expectError: true},
+		{input: "many", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			threshold, err := ParseFailureThreshold(tt.input)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("ParseFailureThreshold() error = %v, expectError %v", err, tt.expectError)
+			}
+			if tt.expectError {
+				return
+			}
+			if limit := threshold.Limit(tt.total); limit != tt.wantLimit {
+				t.Errorf("Expected limit %d, got %d", tt.wantLimit, limit)
+			}
+			if tt.input != "" && threshold.String() != tt.input {
+				t.Errorf("Expected string %s, got %s", tt.input, threshold.String())
+			}
+		})
+	}
+}
+
+func TestPlanBatches(t *testing.T) {
+	var jobs []*batchv1.Job
+	for i := 0; i < 7; i++ {
+		jobs = append(jobs, &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("job-%d", i)}})
+	}
+
+	tests := []struct {
+		name      string
+		opts      BatchOptions
+		wantSizes []int
+	}{
+		{name: "canary then rest", opts: BatchOptions{Canary: 1}, wantSizes: []int{1, 6}},
+		{name: "canary then batches", opts: BatchOptions{Canary: 1, BatchSize: 2}, wantSizes: []int{1, 2, 2, 2}},
+		{name: "batches only", opts: BatchOptions{BatchSize: 3}, wantSizes: []int{3, 3, 1}},
+		{name: "canary larger than targets", opts: BatchOptions{Canary: 10}, wantSizes: []int{7}},
+		{name: "no batching", opts: BatchOptions{}, wantSizes: []int{7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := PlanBatches(jobs, tt.opts)
+			if len(batches) != len(tt.wantSizes) {
+				t.Fatalf("Expected %d batches, got %d", len(tt.wantSizes), len(batches))
+			}
+
+			next := 0
+			for i, batch := range batches {
+				if len(batch) != tt.wantSizes[i] {
+					t.Errorf("Expected batch %d to have %d jobs, got %d", i, tt.wantSizes[i], len(batch))
+				}
+				for _, job := range batch {
+					if job.Name != jobs[next].Name {
+						t.Errorf("Expected %s, got %s", jobs[next].Name, job.Name)
+					}
+					next++
+				}
+			}
+		})
+	}
+}
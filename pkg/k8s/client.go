@@ -1,22 +1,45 @@
 package k8s
 
 import (
-	"path/filepath"
+	"fmt"
+	"sort"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 )
 
 // ClientInterface defines the interface for Kubernetes client operations
 type ClientInterface interface {
 	GetClient() (*kubernetes.Clientset, error)
+	GetConfig() (*rest.Config, error)
+}
+
+// ClientOptions configures how the Kubernetes client connects to the cluster
+type ClientOptions struct {
+	// Kubeconfig is an explicit kubeconfig path. When empty the KUBECONFIG
+	// environment variable (which may list several paths) or ~/.kube/config is used.
+	Kubeconfig string
+	// Context selects a kubeconfig context instead of the current one
+	Context string
+	// Impersonate is the user to act as
+	Impersonate string
+	// ImpersonateGroups are the groups to act as
+	ImpersonateGroups []string
+	// Timeout limits the duration of a single API request; zero means no limit
+	Timeout time.Duration
+	// QPS and Burst configure client-side rate limiting; zero keeps the client-go defaults
+	QPS   float32
+	Burst int
+	// DisableInCluster skips in-cluster configuration detection
+	DisableInCluster bool
 }
 
 // Client implements the ClientInterface
 type Client struct {
 	kubeconfig string
+	options    ClientOptions
 }
 
 // NewClient creates a new Kubernetes client
@@ -26,21 +49,19 @@ func NewClient(kubeconfig string) ClientInterface {
 	}
 }
 
+// NewClientWithOptions creates a new Kubernetes client with explicit connection options
+func NewClientWithOptions(options ClientOptions) ClientInterface {
+	return &Client{
+		kubeconfig: options.Kubeconfig,
+		options:    options,
+	}
+}
+
 // GetClient returns a configured Kubernetes clientset
 func (c *Client) GetClient() (*kubernetes.Clientset, error) {
-	config, err := rest.InClusterConfig()
+	config, err := c.GetConfig()
 	if err != nil {
-		kubeconfig := c.kubeconfig
-		if kubeconfig == "" {
-			if home := homedir.HomeDir(); home != "" {
-				kubeconfig = filepath.Join(home, ".kube", "config")
-			}
-		}
-		
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
@@ -49,4 +70,75 @@ func (c *Client) GetClient() (*kubernetes.Clientset, error) {
 	}
 
 	return clientset, nil
-}
\ No newline at end of file
+}
+
+// GetConfig returns the REST config for the cluster. The in-cluster config is
+// preferred unless it is disabled or a kubeconfig or context was given explicitly.
+func (c *Client) GetConfig() (*rest.Config, error) {
+	if !c.options.DisableInCluster && c.kubeconfig == "" && c.options.Context == "" {
+		if config, err := rest.InClusterConfig(); err == nil {
+			config.Impersonate = rest.ImpersonationConfig{
+				UserName: c.options.Impersonate,
+				Groups:   c.options.ImpersonateGroups,
+			}
+			config.Timeout = c.options.Timeout
+			c.applyRateLimits(config)
+			return config, nil
+		}
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = c.kubeconfig
+
+	rawConfig, err := loadingRules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: c.options.Context,
+	}
+	overrides.AuthInfo.Impersonate = c.options.Impersonate
+	overrides.AuthInfo.ImpersonateGroups = c.options.ImpersonateGroups
+	if c.options.Timeout > 0 {
+		overrides.Timeout = c.options.Timeout.String()
+	}
+
+	config, err := clientcmd.NewNonInteractiveClientConfig(*rawConfig, c.options.Context, overrides, loadingRules).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	c.applyRateLimits(config)
+
+	return config, nil
+}
+
+// applyRateLimits sets the configured client-side rate limits on config
+func (c *Client) applyRateLimits(config *rest.Config) {
+	if c.options.QPS > 0 {
+		config.QPS = c.options.QPS
+	}
+	if c.options.Burst > 0 {
+		config.Burst = c.options.Burst
+	}
+}
+
+// KubeconfigContexts returns the sorted context names defined in the kubeconfig.
+// An empty path uses the KUBECONFIG environment variable or ~/.kube/config.
+func KubeconfigContexts(kubeconfig string) ([]string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfig
+
+	rawConfig, err := loadingRules.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+
+	contexts := make([]string, 0, len(rawConfig.Contexts))
+	for name := range rawConfig.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+
+	return contexts, nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -27,12 +28,12 @@ func TestNewClient(t *testing.T) {
 			if client == nil {
 				t.Fatal("NewClient returned nil")
 			}
-			
+
 			c, ok := client.(*Client)
 			if !ok {
 				t.Fatal("NewClient did not return *Client type")
 			}
-			
+
 			if c.kubeconfig != tt.kubeconfig {
 				t.Errorf("kubeconfig = %v, want %v", c.kubeconfig, tt.kubeconfig)
 			}
@@ -47,7 +48,7 @@ func TestClient_GetClient(t *testing.T) {
 		if home == "" {
 			t.Skip("Skipping test: not in cluster and no HOME directory")
 		}
-		
+
 		kubeconfigPath := filepath.Join(home, ".kube", "config")
 		if _, err := os.Stat(kubeconfigPath); os.IsNotExist(err) {
 			t.Skip("Skipping test: no kubeconfig file found")
@@ -59,4 +60,152 @@ func TestClient_GetClient(t *testing.T) {
 	if err != nil {
 		t.Logf("GetClient error (this might be expected in test environment): %v", err)
 	}
-}
\ No newline at end of file
+}
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: east
+clusters:
+- name: east
+  cluster:
+    server: https://east.example.com
+- name: west
+  cluster:
+    server: https://west.example.com
+contexts:
+- name: east
+  context:
+    cluster: east
+    user: admin
+- name: west
+  context:
+    cluster: west
+    user: admin
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+func writeTestKubeconfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestClient_GetConfig(t *testing.T) {
+	dir := t.TempDir()
+	kubeconfig := writeTestKubeconfig(t, dir, "config", testKubeconfig)
+
+	tests := []struct {
+		name       string
+		options    ClientOptions
+		wantHost   string
+		wantUser   string
+		wantGroups int
+		wantErr    bool
+	}{
+		{
+			name:     "current context",
+			options:  ClientOptions{Kubeconfig: kubeconfig, DisableInCluster: true},
+			wantHost: "https://east.example.com",
+		},
+		{
+			name:     "explicit context",
+			options:  ClientOptions{Kubeconfig: kubeconfig, Context: "west", DisableInCluster: true},
+			wantHost: "https://west.example.com",
+		},
+		{
+			name: "impersonation and timeout",
+			options: ClientOptions{
+				Kubeconfig:        kubeconfig,
+				Impersonate:       "jane",
+				ImpersonateGroups: []string{"ops", "dev"},
+				Timeout:           30 * time.Second,
+				DisableInCluster:  true,
+			},
+			wantHost:   "https://east.example.com",
+			wantUser:   "jane",
+			wantGroups: 2,
+		},
+		{
+			name:     "rate limits",
+			options:  ClientOptions{Kubeconfig: kubeconfig, QPS: 50, Burst: 100, DisableInCluster: true},
+			wantHost: "https://east.example.com",
+		},
+		{
+			name:    "unknown context",
+			options: ClientOptions{Kubeconfig: kubeconfig, Context: "north", DisableInCluster: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewClientWithOptions(tt.options).GetConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.Host != tt.wantHost {
+				t.Errorf("Expected host %s, got %s", tt.wantHost, config.Host)
+			}
+			if config.Impersonate.UserName != tt.wantUser {
+				t.Errorf("Expected impersonated user %q, got %q", tt.wantUser, config.Impersonate.UserName)
+			}
+			if len(config.Impersonate.Groups) != tt.wantGroups {
+				t.Errorf("Expected %d impersonated groups, got %d", tt.wantGroups, len(config.Impersonate.Groups))
+			}
+			if config.Timeout != tt.options.Timeout {
+				t.Errorf("Expected timeout %v, got %v", tt.options.Timeout, config.Timeout)
+			}
+			if config.QPS != tt.options.QPS || config.Burst != tt.options.Burst {
+				t.Errorf("Expected QPS/burst %v/%d, got %v/%d", tt.options.QPS, tt.options.Burst, config.QPS, config.Burst)
+			}
+		})
+	}
+}
+
+func TestClient_GetConfig_KubeconfigEnv(t *testing.T) {
+	dir := t.TempDir()
+	// The first file only defines the current context; the second one holds the rest
+	first := writeTestKubeconfig(t, dir, "first", "apiVersion: v1\nkind: Config\ncurrent-context: west\n")
+	second := writeTestKubeconfig(t, dir, "second", testKubeconfig)
+	t.Setenv("KUBECONFIG", first+string(os.PathListSeparator)+second)
+
+	config, err := NewClientWithOptions(ClientOptions{DisableInCluster: true}).GetConfig()
+	if err != nil {
+		t.Fatalf("GetConfig() error = %v", err)
+	}
+	if config.Host != "https://west.example.com" {
+		t.Errorf("Expected host https://west.example.com, got %s", config.Host)
+	}
+}
+
+func TestKubeconfigContexts(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t, t.TempDir(), "config", testKubeconfig)
+
+	contexts, err := KubeconfigContexts(kubeconfig)
+	if err != nil {
+		t.Fatalf("KubeconfigContexts() error = %v", err)
+	}
+
+	expected := []string{"east", "west"}
+	if len(contexts) != len(expected) {
+		t.Fatalf("Expected %d contexts, got %d", len(expected), len(contexts))
+	}
+	for i := range expected {
+		if contexts[i] != expected[i] {
+			t.Errorf("Expected context %s at index %d, got %s", expected[i], i, contexts[i])
+		}
+	}
+
+	if _, err := KubeconfigContexts(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing kubeconfig but got none")
+	}
+}
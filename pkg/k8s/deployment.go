@@ -3,48 +3,116 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 )
 
+// RevisionAnnotation is set by the deployment controller on each ReplicaSet
+// to the deployment revision it belongs to
+const RevisionAnnotation = "deployment.kubernetes.io/revision"
+
 // DeploymentManagerInterface defines operations for deployment management
 type DeploymentManagerInterface interface {
-	GetPodsFromDeployment(deploymentName, namespace string) ([]corev1.Pod, error)
+	GetPodsFromDeployment(ctx context.Context, deploymentName, namespace string) ([]corev1.Pod, error)
+	GetReplicaSets(ctx context.Context, deploymentName, namespace string) ([]appsv1.ReplicaSet, error)
 	GetNodesFromPods(pods []corev1.Pod) []string
 }
 
 // DeploymentManager manages deployment-related operations
 type DeploymentManager struct {
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 }
 
 // NewDeploymentManager creates a new deployment manager
-func NewDeploymentManager(clientset *kubernetes.Clientset) DeploymentManagerInterface {
+func NewDeploymentManager(clientset kubernetes.Interface) DeploymentManagerInterface {
 	return &DeploymentManager{
 		clientset: clientset,
 	}
 }
 
-// GetPodsFromDeployment returns all pods created by a specific deployment
-func (dm *DeploymentManager) GetPodsFromDeployment(deploymentName, namespace string) ([]corev1.Pod, error) {
-	deployment, err := dm.clientset.AppsV1().Deployments(namespace).Get(context.TODO(), deploymentName, metav1.GetOptions{})
+// GetPodsFromDeployment returns all pods created by a specific deployment.
+// Pods are matched with the deployment's full label selector and then narrowed
+// to those controlled by one of the deployment's ReplicaSets.
+func (dm *DeploymentManager) GetPodsFromDeployment(ctx context.Context, deploymentName, namespace string) ([]corev1.Pod, error) {
+	selector, replicaSets, err := dm.replicaSets(ctx, deploymentName, namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get deployment %s: %v", deploymentName, err)
+		return nil, err
 	}
 
-	labelSelector := metav1.LabelSelector{MatchLabels: deployment.Spec.Selector.MatchLabels}
-	listOptions := metav1.ListOptions{
-		LabelSelector: metav1.FormatLabelSelector(&labelSelector),
+	owners := make(map[types.UID]bool)
+	for i := range replicaSets {
+		owners[replicaSets[i].UID] = true
 	}
 
-	pods, err := dm.clientset.CoreV1().Pods(namespace).List(context.TODO(), listOptions)
+	pods, err := dm.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pods: %v", err)
 	}
 
-	return pods.Items, nil
+	return filterControlledPods(pods.Items, owners), nil
+}
+
+// GetReplicaSets returns the ReplicaSets controlled by a deployment, oldest
+// revision first
+func (dm *DeploymentManager) GetReplicaSets(ctx context.Context, deploymentName, namespace string) ([]appsv1.ReplicaSet, error) {
+	_, replicaSets, err := dm.replicaSets(ctx, deploymentName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(replicaSets, func(i, j int) bool {
+		return ReplicaSetRevision(&replicaSets[i]) < ReplicaSetRevision(&replicaSets[j])
+	})
+	return replicaSets, nil
+}
+
+// ReplicaSetRevision returns the deployment revision of a ReplicaSet, or 0 if
+// it has none
+func ReplicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, err := strconv.ParseInt(rs.Annotations[RevisionAnnotation], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// replicaSets returns the selector of a deployment and the ReplicaSets it controls
+func (dm *DeploymentManager) replicaSets(ctx context.Context, deploymentName, namespace string) (labels.Selector, []appsv1.ReplicaSet, error) {
+	deployment, err := dm.clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get deployment %s: %v", deploymentName, err)
+	}
+
+	if deployment.Spec.Selector == nil {
+		return nil, nil, fmt.Errorf("deployment %s has no selector", deploymentName)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid selector on deployment %s: %v", deploymentName, err)
+	}
+
+	replicaSets, err := dm.clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector.String(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list replicasets: %v", err)
+	}
+
+	controlled := make([]appsv1.ReplicaSet, 0, len(replicaSets.Items))
+	for i := range replicaSets.Items {
+		if metav1.IsControlledBy(&replicaSets.Items[i], deployment) {
+			controlled = append(controlled, replicaSets.Items[i])
+		}
+	}
+	return selector, controlled, nil
 }
 
 // GetNodesFromPods returns unique nodes where pods are running
@@ -61,4 +129,16 @@ func (dm *DeploymentManager) GetNodesFromPods(pods []corev1.Pod) []string {
 		nodes = append(nodes, node)
 	}
 	return nodes
-}
\ No newline at end of file
+}
+
+// filterControlledPods returns the pods whose controller is one of owners
+func filterControlledPods(pods []corev1.Pod, owners map[types.UID]bool) []corev1.Pod {
+	filtered := make([]corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		controllerRef := metav1.GetControllerOf(&pod)
+		if controllerRef != nil && owners[controllerRef.UID] {
+			filtered = append(filtered, pod)
+		}
+	}
+	return filtered
+}
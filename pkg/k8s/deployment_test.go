@@ -1,10 +1,14 @@
 package k8s
 
 import (
+	"context"
 	"testing"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestDeploymentManager_GetNodesFromPods(t *testing.T) {
@@ -93,18 +97,18 @@ func TestDeploymentManager_GetNodesFromPods(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			nodes := dm.GetNodesFromPods(tt.pods)
-			
+
 			if len(nodes) != len(tt.expected) {
 				t.Errorf("GetNodesFromPods() returned %d nodes, expected %d", len(nodes), len(tt.expected))
 				return
 			}
-			
+
 			// Create a map for easy lookup
 			nodeMap := make(map[string]bool)
 			for _, node := range nodes {
 				nodeMap[node] = true
 			}
-			
+
 			// Check all expected nodes are present
 			for _, expectedNode := range tt.expected {
 				if !nodeMap[expectedNode] {
@@ -113,4 +117,67 @@ func TestDeploymentManager_GetNodesFromPods(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestDeploymentManager_GetPodsFromDeployment(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "deploy-uid"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "track", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"canary"}},
+				},
+			},
+		},
+	}
+	deploymentGVK := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	ownedRS := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "web-abc",
+			Namespace:       "default",
+			UID:             "rs-uid",
+			Labels:          map[string]string{"app": "web"},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, deploymentGVK)},
+		},
+	}
+	// A ReplicaSet of another workload whose pods carry the same labels
+	foreignRS := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-xyz",
+			Namespace: "default",
+			UID:       "other-rs-uid",
+			Labels:    map[string]string{"app": "web"},
+		},
+	}
+
+	objects := []runtime.Object{
+		deployment, ownedRS, foreignRS,
+		newTestPod("web-abc-1", map[string]string{"app": "web"}, "node1", ownedRS, "ReplicaSet"),
+		newTestPod("web-abc-2", map[string]string{"app": "web", "track": "stable"}, "node2", ownedRS, "ReplicaSet"),
+		newTestPod("web-canary", map[string]string{"app": "web", "track": "canary"}, "node3", ownedRS, "ReplicaSet"),
+		newTestPod("other-xyz-1", map[string]string{"app": "web"}, "node4", foreignRS, "ReplicaSet"),
+		newTestPod("web-bare", map[string]string{"app": "web"}, "node5", nil, ""),
+	}
+
+	dm := NewDeploymentManager(fake.NewSimpleClientset(objects...))
+
+	pods, err := dm.GetPodsFromDeployment(context.TODO(), "web", "default")
+	if err != nil {
+		t.Fatalf("GetPodsFromDeployment() error = %v", err)
+	}
+
+	expected := map[string]bool{"web-abc-1": true, "web-abc-2": true}
+	if len(pods) != len(expected) {
+		t.Fatalf("Expected %d pods, got %d", len(expected), len(pods))
+	}
+	for _, pod := range pods {
+		if !expected[pod.Name] {
+			t.Errorf("Unexpected pod %s in result", pod.Name)
+		}
+	}
+
+	if _, err := dm.GetPodsFromDeployment(context.TODO(), "missing", "default"); err == nil {
+		t.Error("Expected error for missing deployment but got none")
+	}
+}
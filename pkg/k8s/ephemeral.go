@@ -0,0 +1,262 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EphemeralManagerInterface defines operations for running debug containers inside target pods
+type EphemeralManagerInterface interface {
+	AttachDebugContainers(ctx context.Context, name string, pods []corev1.Pod, opts EphemeralOptions) []DebugContainerAttachment
+	WaitForEphemeralContainerStart(ctx context.Context, ref EphemeralContainerRef, timeout time.Duration) error
+	WaitForEphemeralContainers(ctx context.Context, refs []EphemeralContainerRef, timeout time.Duration) ([]JobResult, error)
+}
+
+// EphemeralOptions configures the debug containers created by AttachDebugContainers
+type EphemeralOptions struct {
+	Image   string
+	Command []string
+	// TargetContainer is the container whose process namespace is shared;
+	// it defaults to the first container of each pod
+	TargetContainer string
+	// RunID makes the container names unique (see EphemeralContainerName);
+	// a random ID is generated when empty
+	RunID string
+}
+
+// EphemeralContainerRef identifies a debug container attached to a pod
+type EphemeralContainerRef struct {
+	Namespace string
+	Pod       string
+	Node      string
+	Container string
+}
+
+// DebugContainerAttachment is the outcome of attaching the debug container to one pod
+type DebugContainerAttachment struct {
+	// Ref identifies the debug container, whether or not it was attached
+	Ref      EphemeralContainerRef
+	Err      error
+	Category ErrorCategory
+}
+
+// Attached reports whether the debug container was attached
+func (a DebugContainerAttachment) Attached() bool {
+	return a.Err == nil
+}
+
+// Creation returns the attachment as a job creation so that failures are
+// reported the same way as jobs
+func (a DebugContainerAttachment) Creation() JobCreation {
+	return JobCreation{
+		Node:      a.Ref.Node,
+		TargetPod: a.Ref.Pod,
+		Job:       a.Ref.Container,
+		Err:       a.Err,
+		Category:  a.Category,
+	}
+}
+
+// AttachedContainers returns the debug containers that were attached
+func AttachedContainers(attachments []DebugContainerAttachment) []EphemeralContainerRef {
+	var refs []EphemeralContainerRef
+	for _, a := range attachments {
+		if a.Attached() {
+			refs = append(refs, a.Ref)
+		}
+	}
+	return refs
+}
+
+// EphemeralManager manages ephemeral debug containers
+type EphemeralManager struct {
+	clientset    kubernetes.Interface
+	pollInterval time.Duration
+}
+
+// NewEphemeralManager creates a new ephemeral container manager
+func NewEphemeralManager(clientset kubernetes.Interface) EphemeralManagerInterface {
+	return &EphemeralManager{
+		clientset: clientset,
+	}
+}
+
+// AttachDebugContainers adds a debug container to each pod through the
+// ephemeralcontainers subresource. Ephemeral containers cannot be removed, so
+// every container gets a name unique to the run, derived from name and the run ID.
+// One outcome is returned per pod, in order; pods not attempted because ctx was
+// canceled are reported as Canceled.
+func (em *EphemeralManager) AttachDebugContainers(ctx context.Context, name string, pods []corev1.Pod, opts EphemeralOptions) []DebugContainerAttachment {
+	command := opts.Command
+	if len(command) == 0 {
+		command = defaultJobCommand
+	}
+
+	runID := opts.RunID
+	if runID == "" {
+		runID = NewRunID()
+	}
+	containerName := EphemeralContainerName(name, runID)
+
+	attachments := make([]DebugContainerAttachment, 0, len(pods))
+	for _, target := range pods {
+		ref := EphemeralContainerRef{
+			Namespace: target.Namespace,
+			Pod:       target.Name,
+			Node:      target.Spec.NodeName,
+			Container: containerName,
+		}
+		err := em.attachDebugContainer(ctx, target, containerName, command, opts)
+		attachments = append(attachments, DebugContainerAttachment{Ref: ref, Err: err, Category: CategorizeError(err)})
+	}
+	return attachments
+}
+
+// attachDebugContainer adds the debug container to the latest version of a
+// pod. API errors are returned unwrapped so that they can be categorized.
+func (em *EphemeralManager) attachDebugContainer(ctx context.Context, target corev1.Pod, containerName string, command []string, opts EphemeralOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Work on the latest version of the pod to avoid update conflicts
+	pod, err := em.clientset.CoreV1().Pods(target.Namespace).Get(ctx, target.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	targetContainer := opts.TargetContainer
+	if targetContainer == "" && len(pod.Spec.Containers) > 0 {
+		targetContainer = pod.Spec.Containers[0].Name
+	}
+
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:    containerName,
+			Image:   opts.Image,
+			Command: command,
+		},
+		TargetContainerName: targetContainer,
+	})
+
+	_, err = em.clientset.CoreV1().Pods(pod.Namespace).UpdateEphemeralContainers(ctx, pod.Name, pod, metav1.UpdateOptions{})
+	return err
+}
+
+// WaitForEphemeralContainerStart waits until the debug container is running or
+// has terminated, so that its logs can be read. A zero timeout waits indefinitely.
+func (em *EphemeralManager) WaitForEphemeralContainerStart(ctx context.Context, ref EphemeralContainerRef, timeout time.Duration) error {
+	ctx, cancel := waitContext(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollUntilContextCancel(ctx, em.interval(), true, func(ctx context.Context) (bool, error) {
+		pod, err := em.clientset.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Pod, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		status := ephemeralContainerStatus(pod, ref.Container)
+		return status != nil && (status.State.Running != nil || status.State.Terminated != nil), nil
+	})
+	if err != nil {
+		return fmt.Errorf("debug container %s in pod %s did not start: %v", ref.Container, ref.Pod, err)
+	}
+
+	return nil
+}
+
+// WaitForEphemeralContainers waits until every debug container terminates or
+// the timeout expires. Results are returned in the order of refs.
+func (em *EphemeralManager) WaitForEphemeralContainers(ctx context.Context, refs []EphemeralContainerRef, timeout time.Duration) ([]JobResult, error) {
+	results := make([]JobResult, len(refs))
+	pending := make(map[int]bool, len(refs))
+	for i, ref := range refs {
+		results[i] = ephemeralResult(ref)
+		pending[i] = true
+	}
+
+	waitCtx, cancel := waitContext(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollUntilContextCancel(waitCtx, em.interval(), true, func(ctx context.Context) (bool, error) {
+		for i := range pending {
+			ref := refs[i]
+			pod, err := em.clientset.CoreV1().Pods(ref.Namespace).Get(ctx, ref.Pod, metav1.GetOptions{})
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					results[i].Phase = JobPhaseUnknown
+					delete(pending, i)
+				} else if category := CategorizeError(err); category.Terminal() {
+					results[i].Phase = JobPhaseUnknown
+					results[i].Message = fmt.Sprintf("failed to get pod [%s]: %v", category, err)
+					delete(pending, i)
+				}
+				// Other errors are treated as transient and retried on the next tick
+				continue
+			}
+
+			status := ephemeralContainerStatus(pod, ref.Container)
+			if status == nil || status.State.Terminated == nil {
+				continue
+			}
+			terminated := status.State.Terminated
+			exitCode := terminated.ExitCode
+			results[i].ExitCode = &exitCode
+			results[i].Duration = terminated.FinishedAt.Sub(terminated.StartedAt.Time)
+			results[i].Phase = JobPhaseSucceeded
+			if exitCode != 0 {
+				results[i].Phase = JobPhaseFailed
+			}
+			delete(pending, i)
+		}
+		return len(pending) == 0, nil
+	})
+	if ctx.Err() != nil {
+		return results, fmt.Errorf("stopped waiting for debug containers: %v", ctx.Err())
+	}
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return results, fmt.Errorf("failed to wait for debug containers: %v", err)
+	}
+
+	for i := range pending {
+		results[i].Phase = JobPhaseTimeout
+	}
+
+	return results, nil
+}
+
+// interval returns the poll interval
+func (em *EphemeralManager) interval() time.Duration {
+	if em.pollInterval == 0 {
+		return defaultPollInterval
+	}
+	return em.pollInterval
+}
+
+// ephemeralResult builds the initial JobResult for a debug container
+func ephemeralResult(ref EphemeralContainerRef) JobResult {
+	return JobResult{
+		Node:      ref.Node,
+		TargetPod: ref.Pod,
+		Job:       ref.Container,
+		Pod:       ref.Pod,
+		Container: ref.Container,
+	}
+}
+
+// ephemeralContainerStatus returns the status of the named ephemeral container, or nil
+func ephemeralContainerStatus(pod *corev1.Pod, name string) *corev1.ContainerStatus {
+	for i := range pod.Status.EphemeralContainerStatuses {
+		if pod.Status.EphemeralContainerStatuses[i].Name == name {
+			return &pod.Status.EphemeralContainerStatuses[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestTargetPod(name, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName:   node,
+			Containers: []corev1.Container{{Name: "app", Image: "nginx"}},
+		},
+	}
+}
+
+func TestEphemeralManager_AttachDebugContainers_Canceled(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestTargetPod("web-1", "node1"))
+	em := &EphemeralManager{clientset: clientset}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attachments := em.AttachDebugContainers(ctx, "debug", []corev1.Pod{*newTestTargetPod("web-1", "node1")}, EphemeralOptions{Image: "busybox"})
+	if len(attachments) != 1 || attachments[0].Category != ErrorCategoryCanceled {
+		t.Errorf("Expected a Canceled outcome, got %+v", attachments)
+	}
+}
+
+func TestEphemeralManager_AttachDebugContainers(t *testing.T) {
+	tests := []struct {
+		name            string
+		targetContainer string
+		wantTarget      string
+	}{
+		{name: "default target container", wantTarget: "app"},
+		{name: "explicit target container", targetContainer: "sidecar", wantTarget: "sidecar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(newTestTargetPod("web-1", "node1"), newTestTargetPod("web-2", "node2"))
+			em := &EphemeralManager{clientset: clientset}
+
+			pods := []corev1.Pod{*newTestTargetPod("web-1", "node1"), *newTestTargetPod("web-2", "node2"), *newTestTargetPod("gone", "node3")}
+			attachments := em.AttachDebugContainers(context.TODO(), "debug", pods, EphemeralOptions{
+				Image:           "busybox",
+				Command:         []string{"ps", "aux"},
+				TargetContainer: tt.targetContainer,
+				RunID:           "abc123",
+			})
+			if len(attachments) != len(pods) {
+				t.Fatalf("Expected %d outcomes, got %d", len(pods), len(attachments))
+			}
+
+			// The missing pod is reported with its category
+			gone := attachments[2]
+			if gone.Attached() || gone.Category != ErrorCategoryNotFound || gone.Ref.Pod != "gone" || gone.Ref.Node != "node3" {
+				t.Errorf("Expected a NotFound failure for pod gone, got %+v", gone)
+			}
+			refs := AttachedContainers(attachments)
+			if len(refs) != 2 {
+				t.Fatalf("Expected 2 debug containers, got %d", len(refs))
+			}
+
+			for _, ref := range refs {
+				if ref.Container != "debug-abc123" {
+					t.Errorf("Expected container name debug-abc123, got %s", ref.Container)
+				}
+				pod, err := clientset.CoreV1().Pods("default").Get(context.TODO(), ref.Pod, metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("Failed to get pod %s: %v", ref.Pod, err)
+				}
+				if ref.Node != pod.Spec.NodeName {
+					t.Errorf("Expected node %s, got %s", pod.Spec.NodeName, ref.Node)
+				}
+				if len(pod.Spec.EphemeralContainers) != 1 {
+					t.Fatalf("Expected 1 ephemeral container on %s, got %d", ref.Pod, len(pod.Spec.EphemeralContainers))
+				}
+				container := pod.Spec.EphemeralContainers[0]
+				if container.Name != ref.Container || container.Image != "busybox" || container.TargetContainerName != tt.wantTarget {
+					t.Errorf("Unexpected ephemeral container %+v", container)
+				}
+			}
+		})
+	}
+}
+
+func TestEphemeralManager_WaitForEphemeralContainers(t *testing.T) {
+	finished := func(name string, exitCode int32) corev1.ContainerStatus {
+		start := metav1.NewTime(time.Now().Add(-5 * time.Second))
+		return corev1.ContainerStatus{
+			Name: name,
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, StartedAt: start, FinishedAt: metav1.Now()},
+			},
+		}
+	}
+
+	succeeded := newTestTargetPod("web-1", "node1")
+	succeeded.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{finished("debug-1", 0)}
+	failed := newTestTargetPod("web-2", "node2")
+	failed.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{finished("debug-2", 2)}
+	running := newTestTargetPod("web-3", "node3")
+	running.Status.EphemeralContainerStatuses = []corev1.ContainerStatus{
+		{Name: "debug-3", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+	}
+
+	em := &EphemeralManager{clientset: fake.NewSimpleClientset(succeeded, failed, running), pollInterval: 10 * time.Millisecond}
+
+	refs := []EphemeralContainerRef{
+		{Namespace: "default", Pod: "web-1", Node: "node1", Container: "debug-1"},
+		{Namespace: "default", Pod: "web-2", Node: "node2", Container: "debug-2"},
+		{Namespace: "default", Pod: "web-3", Node: "node3", Container: "debug-3"},
+		{Namespace: "default", Pod: "gone", Node: "node4", Container: "debug-4"},
+	}
+
+	if err := em.WaitForEphemeralContainerStart(context.TODO(), refs[2], time.Second); err != nil {
+		t.Errorf("WaitForEphemeralContainerStart() error = %v", err)
+	}
+
+	results, err := em.WaitForEphemeralContainers(context.TODO(), refs, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForEphemeralContainers() error = %v", err)
+	}
+
+	wantPhase := []JobPhase{JobPhaseSucceeded, JobPhaseFailed, JobPhaseTimeout, JobPhaseUnknown}
+	for i, result := range results {
+		if result.Phase != wantPhase[i] {
+			t.Errorf("Expected phase %s for %s, got %s", wantPhase[i], result.Target(), result.Phase)
+		}
+		if result.Container != refs[i].Container || result.Pod != refs[i].Pod || result.TargetPod != refs[i].Pod {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	}
+	if results[1].ExitCode == nil || *results[1].ExitCode != 2 {
+		t.Errorf("Expected exit code 2, got %v", results[1].ExitCode)
+	}
+	if results[0].Duration <= 0 {
+		t.Errorf("Expected a positive duration, got %v", results[0].Duration)
+	}
+}
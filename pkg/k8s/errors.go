@@ -0,0 +1,61 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ErrorCategory classifies API errors so that failures can be grouped and acted on
+type ErrorCategory string
+
+const (
+	// ErrorCategoryForbidden means RBAC or an admission policy rejected the request
+	ErrorCategoryForbidden ErrorCategory = "Forbidden"
+	// ErrorCategoryQuotaExceeded means a ResourceQuota in the namespace was exhausted
+	ErrorCategoryQuotaExceeded ErrorCategory = "QuotaExceeded"
+	// ErrorCategoryAlreadyExists means an object with the same name already exists
+	ErrorCategoryAlreadyExists ErrorCategory = "AlreadyExists"
+	// ErrorCategoryInvalid means the object failed validation
+	ErrorCategoryInvalid ErrorCategory = "Invalid"
+	// ErrorCategoryNotFound means a referenced object such as the namespace does not exist
+	ErrorCategoryNotFound ErrorCategory = "NotFound"
+	// ErrorCategoryUnavailable means the API server timed out, throttled or was unreachable
+	ErrorCategoryUnavailable ErrorCategory = "Unavailable"
+	// ErrorCategoryCanceled means the request was not made or completed because the run was canceled
+	ErrorCategoryCanceled ErrorCategory = "Canceled"
+	// ErrorCategoryUnknown is used for all other errors
+	ErrorCategoryUnknown ErrorCategory = "Unknown"
+)
+
+// Terminal reports whether retrying a request that failed with this category
+// cannot succeed without a change by the user, such as granting RBAC
+func (c ErrorCategory) Terminal() bool {
+	return c == ErrorCategoryForbidden || c == ErrorCategoryInvalid
+}
+
+// CategorizeError returns the category of an API error, or "" for a nil error
+func CategorizeError(err error) ErrorCategory {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return ErrorCategoryCanceled
+	case apierrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota"):
+		return ErrorCategoryQuotaExceeded
+	case apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err):
+		return ErrorCategoryForbidden
+	case apierrors.IsAlreadyExists(err):
+		return ErrorCategoryAlreadyExists
+	case apierrors.IsInvalid(err):
+		return ErrorCategoryInvalid
+	case apierrors.IsNotFound(err):
+		return ErrorCategoryNotFound
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err), apierrors.IsServiceUnavailable(err):
+		return ErrorCategoryUnavailable
+	default:
+		return ErrorCategoryUnknown
+	}
+}
@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestCategorizeError(t *testing.T) {
+	jobs := schema.GroupResource{Group: "batch", Resource: "jobs"}
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{name: "nil", err: nil, want: ""},
+		{name: "forbidden", err: apierrors.NewForbidden(jobs, "task", fmt.Errorf("cannot create jobs")), want: ErrorCategoryForbidden},
+		{name: "quota exceeded", err: apierrors.NewForbidden(jobs, "task", fmt.Errorf("exceeded quota: compute, requested: count/jobs.batch=1")), want: ErrorCategoryQuotaExceeded},
+		{name: "already exists", err: apierrors.NewAlreadyExists(jobs, "task"), want: ErrorCategoryAlreadyExists},
+		{name: "invalid", err: apierrors.NewInvalid(schema.GroupKind{Group: "batch", Kind: "Job"}, "task", field.ErrorList{field.Required(field.NewPath("spec"), "")}), want: ErrorCategoryInvalid},
+		{name: "namespace not found", err: apierrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "missing"), want: ErrorCategoryNotFound},
+		{name: "throttled", err: apierrors.NewTooManyRequests("slow down", 1), want: ErrorCategoryUnavailable},
+		{name: "client timeout", err: fmt.Errorf("create job: %w", context.DeadlineExceeded), want: ErrorCategoryUnavailable},
+		{name: "canceled", err: fmt.Errorf("create job: %w", context.Canceled), want: ErrorCategoryCanceled},
+		{name: "other", err: fmt.Errorf("connection reset"), want: ErrorCategoryUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CategorizeError(tt.err); got != tt.want {
+				t.Errorf("CategorizeError() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCategory_Terminal(t *testing.T) {
+	for _, category := range []ErrorCategory{ErrorCategoryForbidden, ErrorCategoryInvalid} {
+		if !category.Terminal() {
+			t.Errorf("Expected %s to be terminal", category)
+		}
+	}
+	for _, category := range []ErrorCategory{ErrorCategoryUnavailable, ErrorCategoryNotFound, ErrorCategoryUnknown} {
+		if category.Terminal() {
+			t.Errorf("Expected %s to be retried", category)
+		}
+	}
+}
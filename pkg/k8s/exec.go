@@ -0,0 +1,194 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// defaultExecParallelism is how many pods are exec'd into at once when no limit is given
+const defaultExecParallelism = 10
+
+// ExecManagerInterface defines operations for running commands inside existing pods
+type ExecManagerInterface interface {
+	ExecInPods(ctx context.Context, pods []corev1.Pod, opts ExecOptions) []ExecResult
+}
+
+// ExecOptions configures ExecInPods
+type ExecOptions struct {
+	Command []string
+	// Container to exec into; defaults to the first container of each pod
+	Container string
+	// Parallelism bounds how many pods are exec'd into concurrently
+	Parallelism int
+	// Timeout limits each exec; zero means no limit
+	Timeout time.Duration
+}
+
+// ExecResult holds the outcome of running a command in one pod
+type ExecResult struct {
+	Pod       string
+	Namespace string
+	Node      string
+	Container string
+	// ExitCode is the command's exit status, or -1 if it could not be determined
+	ExitCode int
+	Stdout   []byte
+	Stderr   []byte
+	Duration time.Duration
+	// Err is set when the command did not run to completion or exited non-zero
+	Err error
+}
+
+// Succeeded reports whether the command exited with status zero
+func (r ExecResult) Succeeded() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// executorFactory creates a remote command executor for an exec URL
+type executorFactory func(config *rest.Config, u *url.URL) (remotecommand.Executor, error)
+
+// ExecManager runs commands in pods over the pods/exec subresource
+type ExecManager struct {
+	config      *rest.Config
+	newExecutor executorFactory
+}
+
+// NewExecManager creates a new exec manager
+func NewExecManager(config *rest.Config) ExecManagerInterface {
+	return &ExecManager{
+		config:      config,
+		newExecutor: newFallbackExecutor,
+	}
+}
+
+// newFallbackExecutor prefers the websocket protocol and falls back to SPDY
+// for API servers that do not support it
+func newFallbackExecutor(config *rest.Config, u *url.URL) (remotecommand.Executor, error) {
+	websocketExec, err := remotecommand.NewWebSocketExecutor(config, "GET", u.String())
+	if err != nil {
+		return nil, err
+	}
+	spdyExec, err := remotecommand.NewSPDYExecutor(config, "POST", u)
+	if err != nil {
+		return nil, err
+	}
+	return remotecommand.NewFallbackExecutor(websocketExec, spdyExec, httpstream.IsUpgradeFailure)
+}
+
+// ExecInPods runs the command in every pod with bounded concurrency.
+// Results are returned in the order of pods.
+func (em *ExecManager) ExecInPods(ctx context.Context, pods []corev1.Pod, opts ExecOptions) []ExecResult {
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultExecParallelism
+	}
+
+	results := make([]ExecResult, len(pods))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+
+	for i := range pods {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = em.execInPod(ctx, &pods[i], opts)
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// execInPod runs the command in a single pod and captures its output
+func (em *ExecManager) execInPod(ctx context.Context, pod *corev1.Pod, opts ExecOptions) ExecResult {
+	container := opts.Container
+	if container == "" && len(pod.Spec.Containers) > 0 {
+		container = pod.Spec.Containers[0].Name
+	}
+
+	result := ExecResult{
+		Pod:       pod.Name,
+		Namespace: pod.Namespace,
+		Node:      pod.Spec.NodeName,
+		Container: container,
+		ExitCode:  -1,
+	}
+
+	u, err := execURL(em.config, pod.Namespace, pod.Name, container, opts.Command)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	executor, err := em.newExecutor(em.config, u)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to create executor for pod %s: %v", pod.Name, err)
+		return result
+	}
+
+	ctx, cancel := waitContext(ctx, opts.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	start := time.Now()
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+
+	var exitErr exec.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+		result.Err = fmt.Errorf("command exited with code %d", result.ExitCode)
+	default:
+		result.Err = fmt.Errorf("failed to exec in pod %s: %v", pod.Name, err)
+	}
+
+	return result
+}
+
+// execURL builds the pods/exec URL for a command
+func execURL(config *rest.Config, namespace, pod, container string, command []string) (*url.URL, error) {
+	cfg := *config
+	cfg.APIPath = "/api"
+	cfg.GroupVersion = &corev1.SchemeGroupVersion
+
+	base, versionedAPIPath, err := rest.DefaultServerUrlFor(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL: %v", err)
+	}
+
+	return rest.NewRequestWithClient(base, versionedAPIPath, rest.ClientContentConfig{GroupVersion: corev1.SchemeGroupVersion}, nil).
+		Verb("POST").
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec).
+		URL(), nil
+}
@@ -0,0 +1,149 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// fakeExecutor writes canned output and returns a canned error
+type fakeExecutor struct {
+	stdout string
+	stderr string
+	err    error
+	run    func()
+}
+
+func (f *fakeExecutor) Stream(options remotecommand.StreamOptions) error {
+	return f.StreamWithContext(context.TODO(), options)
+}
+
+func (f *fakeExecutor) StreamWithContext(ctx context.Context, options remotecommand.StreamOptions) error {
+	if f.run != nil {
+		f.run()
+	}
+	fmt.Fprint(options.Stdout, f.stdout)
+	fmt.Fprint(options.Stderr, f.stderr)
+	return f.err
+}
+
+func newTestExecPod(name string, containers ...string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "node-" + name},
+	}
+	for _, c := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: c})
+	}
+	return pod
+}
+
+func TestExecManager_ExecInPods(t *testing.T) {
+	executors := map[string]*fakeExecutor{
+		"web-1": {stdout: "ok\n"},
+		"web-2": {stdout: "partial\n", stderr: "boom\n", err: exec.CodeExitError{Err: fmt.Errorf("exit 3"), Code: 3}},
+		"web-3": {err: fmt.Errorf("connection refused")},
+	}
+
+	var urls sync.Map
+	em := &ExecManager{
+		config: &rest.Config{Host: "https://cluster.example"},
+		newExecutor: func(config *rest.Config, u *url.URL) (remotecommand.Executor, error) {
+			pod := strings.Split(u.Path, "/")[6]
+			urls.Store(pod, u)
+			return executors[pod], nil
+		},
+	}
+
+	pods := []corev1.Pod{
+		newTestExecPod("web-1", "app", "sidecar"),
+		newTestExecPod("web-2", "app"),
+		newTestExecPod("web-3", "app"),
+	}
+
+	results := em.ExecInPods(context.TODO(), pods, ExecOptions{Command: []string{"cat", "/etc/config"}, Container: "app"})
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	if !results[0].Succeeded() || results[0].ExitCode != 0 || string(results[0].Stdout) != "ok\n" {
+		t.Errorf("Unexpected result for web-1: %+v", results[0])
+	}
+	if results[0].Node != "node-web-1" || results[0].Container != "app" {
+		t.Errorf("Unexpected node/container for web-1: %s/%s", results[0].Node, results[0].Container)
+	}
+	if results[1].Succeeded() || results[1].ExitCode != 3 || string(results[1].Stderr) != "boom\n" {
+		t.Errorf("Unexpected result for web-2: %+v", results[1])
+	}
+	if results[2].Succeeded() || results[2].ExitCode != -1 || results[2].Err == nil {
+		t.Errorf("Unexpected result for web-3: %+v", results[2])
+	}
+
+	value, ok := urls.Load("web-1")
+	if !ok {
+		t.Fatal("Expected an exec URL for web-1")
+	}
+	u := value.(*url.URL)
+	if u.Path != "/api/v1/namespaces/default/pods/web-1/exec" {
+		t.Errorf("Unexpected exec path %s", u.Path)
+	}
+	query := u.Query()
+	if query.Get("container") != "app" || strings.Join(query["command"], " ") != "cat /etc/config" || query.Get("stdout") != "true" {
+		t.Errorf("Unexpected exec query %s", u.RawQuery)
+	}
+}
+
+func TestExecManager_Parallelism(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+
+	em := &ExecManager{
+		config: &rest.Config{Host: "https://cluster.example"},
+		newExecutor: func(config *rest.Config, u *url.URL) (remotecommand.Executor, error) {
+			return &fakeExecutor{run: func() {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+			}}, nil
+		},
+	}
+
+	var pods []corev1.Pod
+	for i := 0; i < 8; i++ {
+		pods = append(pods, newTestExecPod(fmt.Sprintf("web-%d", i), "app"))
+	}
+
+	results := em.ExecInPods(context.TODO(), pods, ExecOptions{Command: []string{"true"}, Parallelism: 3})
+
+	if maxRunning > 3 {
+		t.Errorf("Expected at most 3 concurrent execs, got %d", maxRunning)
+	}
+	for i, result := range results {
+		if result.Pod != pods[i].Name {
+			t.Errorf("Expected result %d for %s, got %s", i, pods[i].Name, result.Pod)
+		}
+		// The first container is used when none is given
+		if result.Container != "app" {
+			t.Errorf("Expected container app, got %s", result.Container)
+		}
+	}
+}
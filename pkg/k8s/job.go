@@ -3,23 +3,190 @@ package k8s
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 )
 
+// NodeAnnotation records the node a job was created for
+const NodeAnnotation = "deployment-inspector/node"
+
+// PodAnnotation records the target pod a per-pod job was created for
+const PodAnnotation = "deployment-inspector/pod"
+
+// JobContainerName is the name of the container running the task in every job
+const JobContainerName = "job-container"
+
+// HostRootMountPath is where host mode mounts the node's root filesystem
+const HostRootMountPath = "/host"
+
+// defaultJobParallelism is how many jobs are created concurrently when no limit is given
+const defaultJobParallelism = 10
+
+// defaultJobCommand is run when no command is given
+var defaultJobCommand = []string{"echo", "Job running on node"}
+
 // JobManagerInterface defines operations for job management
 type JobManagerInterface interface {
-	CreateJobOnNodes(jobName string, nodes []string, namespace, image string, command []string, tolerations []corev1.Toleration) ([]string, error)
+	CreateJobOnNodes(ctx context.Context, jobName string, nodes []string, namespace, image string, command []string, tolerations []corev1.Toleration) ([]string, error)
+	CreateJobs(ctx context.Context, jobName string, targets []JobTarget, opts JobOptions) ([]JobCreation, error)
+	BuildJobs(jobName string, targets []JobTarget, opts JobOptions) ([]*batchv1.Job, error)
+	SubmitJobs(ctx context.Context, jobs []*batchv1.Job, parallelism int) []JobCreation
+	DryRunJobs(ctx context.Context, jobs []*batchv1.Job, parallelism int) []JobCreation
+	ActiveJobs(ctx context.Context, namespace, jobName string, workload WorkloadRef) (map[string]string, error)
+	WaitForJobs(ctx context.Context, namespace string, jobs []JobCreation, timeout time.Duration) ([]JobResult, error)
+}
+
+// JobTarget is a node to run a job on, together with the target pods scheduled there
+type JobTarget struct {
+	Node string
+	Pods []corev1.Pod
+	// Pod is the single target pod in per-pod mode; its identity is injected as env vars
+	Pod *corev1.Pod
+	// Hostname is the kubernetes.io/hostname label of the node (see SetHostnames);
+	// the node name is used when empty
+	Hostname string
+	// Tolerations are added to JobOptions.Tolerations for this target (see TolerateNodeTaints)
+	Tolerations []corev1.Toleration
+}
+
+// JobCreation is the outcome of creating the job for one target
+type JobCreation struct {
+	Node string
+	// TargetPod is the target pod of a per-pod job
+	TargetPod string
+	// Job is the name of the job, whether or not it was created
+	Job      string
+	Err      error
+	Category ErrorCategory
+	// Object is the job as returned by the API server, including defaults and
+	// admission webhook mutations. It is nil when creation failed.
+	Object *batchv1.Job
+}
+
+// Created reports whether the job was created
+func (c JobCreation) Created() bool {
+	return c.Err == nil
+}
+
+// Target identifies the target of the job, as node or node/pod
+func (c JobCreation) Target() string {
+	return TargetName(c.Node, c.TargetPod)
+}
+
+// CreationErrors lists every job that could not be created
+type CreationErrors []JobCreation
+
+// Error summarizes every failed creation with its target and category
+func (e CreationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, c := range e {
+		messages = append(messages, fmt.Sprintf("%s (%s): %v", c.Target(), c.Category, c.Err))
+	}
+	return fmt.Sprintf("failed to create %d jobs: %s", len(e), strings.Join(messages, "; "))
+}
+
+// CreatedJobs returns the names of the jobs that were created, in order
+func CreatedJobs(creations []JobCreation) []string {
+	var names []string
+	for _, c := range creations {
+		if c.Created() {
+			names = append(names, c.Job)
+		}
+	}
+	return names
+}
+
+// SuccessfulCreations returns the creations of the jobs that were created, in order
+func SuccessfulCreations(creations []JobCreation) []JobCreation {
+	var created []JobCreation
+	for _, c := range creations {
+		if c.Created() {
+			created = append(created, c)
+		}
+	}
+	return created
+}
+
+// CreationFailures returns the failed creations, or nil if every job was created
+func CreationFailures(creations []JobCreation) CreationErrors {
+	var failures CreationErrors
+	for _, c := range creations {
+		if !c.Created() {
+			failures = append(failures, c)
+		}
+	}
+	return failures
+}
+
+// JobOptions configures the jobs created by CreateJobs
+type JobOptions struct {
+	Namespace   string
+	Image       string
+	Command     []string
+	Tolerations []corev1.Toleration
+	// Workload is the workload whose nodes are targeted; it is exposed to task templates
+	Workload WorkloadRef
+	// Task, if set, is rendered per target and merged into the generated pod template
+	Task *Task
+	// Parallelism bounds how many jobs are created concurrently
+	Parallelism int
+	// Host runs the job privileged in the node's PID and network namespaces
+	// with the node's root filesystem mounted read-only at HostRootMountPath
+	Host bool
+	// RunID is shared by all jobs of one run and is part of their names;
+	// a random ID is generated if empty
+	RunID string
+	// Version is the tool version recorded on the jobs
+	Version string
+	// Placement is how job pods are pinned to their node (default PlacementHostnameLabel)
+	Placement Placement
+	// Settings are the job-level settings; a task file's job settings take precedence
+	Settings JobSettings
+	// Resources, ImagePullPolicy, PriorityClassName, ServiceAccountName and
+	// ImagePullSecrets are set on the job container and pod when non-empty
+	Resources          corev1.ResourceRequirements
+	ImagePullPolicy    corev1.PullPolicy
+	PriorityClassName  string
+	ServiceAccountName string
+	ImagePullSecrets   []corev1.LocalObjectReference
+}
+
+// JobSettings are job-level settings. Nil fields keep the defaults: jobs are
+// deleted 5 minutes after finishing and the Kubernetes defaults apply otherwise.
+type JobSettings struct {
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+	BackoffLimit            *int32 `json:"backoffLimit,omitempty"`
+	ActiveDeadlineSeconds   *int64 `json:"activeDeadlineSeconds,omitempty"`
+}
+
+// apply sets the non-nil settings on spec
+func (s JobSettings) apply(spec *batchv1.JobSpec) {
+	if s.TTLSecondsAfterFinished != nil {
+		ttl := *s.TTLSecondsAfterFinished
+		spec.TTLSecondsAfterFinished = &ttl
+	}
+	if s.BackoffLimit != nil {
+		backoffLimit := *s.BackoffLimit
+		spec.BackoffLimit = &backoffLimit
+	}
+	if s.ActiveDeadlineSeconds != nil {
+		deadline := *s.ActiveDeadlineSeconds
+		spec.ActiveDeadlineSeconds = &deadline
+	}
 }
 
 // JobManager manages job-related operations
 type JobManager struct {
-	clientset kubernetes.Interface
+	clientset    kubernetes.Interface
+	pollInterval time.Duration
 }
 
 // NewJobManager creates a new job manager
@@ -29,64 +196,383 @@ func NewJobManager(clientset kubernetes.Interface) JobManagerInterface {
 	}
 }
 
-// CreateJobOnNodes creates jobs on specified nodes
-func (jm *JobManager) CreateJobOnNodes(jobName string, nodes []string, namespace, image string, command []string, tolerations []corev1.Toleration) ([]string, error) {
-	if len(command) == 0 {
-		command = []string{"echo", "Job running on node"}
+// NodeTargets groups scheduled pods by node, sorted by node name
+func NodeTargets(pods []corev1.Pod) []JobTarget {
+	podsByNode := make(map[string][]corev1.Pod)
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+		}
+	}
+
+	targets := make([]JobTarget, 0, len(podsByNode))
+	for node, nodePods := range podsByNode {
+		targets = append(targets, JobTarget{Node: node, Pods: nodePods})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Node < targets[j].Node
+	})
+	return targets
+}
+
+// PodTargets returns one target per scheduled pod, sorted by node and pod name
+func PodTargets(pods []corev1.Pod) []JobTarget {
+	targets := make([]JobTarget, 0, len(pods))
+	for i := range pods {
+		pod := pods[i]
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		targets = append(targets, JobTarget{Node: pod.Spec.NodeName, Pods: []corev1.Pod{pod}, Pod: &pod})
 	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Node != targets[j].Node {
+			return targets[i].Node < targets[j].Node
+		}
+		return targets[i].Pod.Name < targets[j].Pod.Name
+	})
+	return targets
+}
 
-	var jobsCreated []string
-	var lastError error
+// TargetName identifies a job target as node, or node/pod in per-pod mode
+func TargetName(node, pod string) string {
+	if pod == "" {
+		return node
+	}
+	return node + "/" + pod
+}
+
+// JobTargetName returns the target a generated job was created for, as node or node/pod
+func JobTargetName(job *batchv1.Job) string {
+	return TargetName(job.Annotations[NodeAnnotation], job.Annotations[PodAnnotation])
+}
 
+// CreateJobOnNodes creates jobs on specified nodes and returns the names of
+// the created jobs. If any job could not be created the error is a CreationErrors.
+func (jm *JobManager) CreateJobOnNodes(ctx context.Context, jobName string, nodes []string, namespace, image string, command []string, tolerations []corev1.Toleration) ([]string, error) {
+	targets := make([]JobTarget, 0, len(nodes))
 	for _, node := range nodes {
-		rand.Seed(time.Now().UnixNano())
-		randomSuffix := fmt.Sprintf("%06d", rand.Intn(1000000))
-		jobInstanceName := fmt.Sprintf("%s-%s", jobName, randomSuffix)
-		
-		ttlSecondsAfterFinished := int32(300) // 5 minutes after completion
-		job := &batchv1.Job{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      jobInstanceName,
-				Namespace: namespace,
-			},
-			Spec: batchv1.JobSpec{
-				TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
-				Template: corev1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels: map[string]string{
-							"job-name": jobInstanceName,
-						},
-					},
-					Spec: corev1.PodSpec{
-						RestartPolicy: corev1.RestartPolicyNever,
-						NodeSelector: map[string]string{
-							"kubernetes.io/hostname": node,
-						},
-						Tolerations: tolerations,
-						Containers: []corev1.Container{
-							{
-								Name:    "job-container",
-								Image:   image,
-								Command: command,
-							},
+		targets = append(targets, JobTarget{Node: node})
+	}
+
+	creations, err := jm.CreateJobs(ctx, jobName, targets, JobOptions{
+		Namespace:   namespace,
+		Image:       image,
+		Command:     command,
+		Tolerations: tolerations,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if failures := CreationFailures(creations); failures != nil {
+		return CreatedJobs(creations), failures
+	}
+	return CreatedJobs(creations), nil
+}
+
+// CreateJobs creates one job per target and returns the outcome for each target.
+// An error is returned only if the jobs could not be generated.
+func (jm *JobManager) CreateJobs(ctx context.Context, jobName string, targets []JobTarget, opts JobOptions) ([]JobCreation, error) {
+	jobs, err := jm.BuildJobs(jobName, targets, opts)
+	if err != nil {
+		return nil, err
+	}
+	return jm.SubmitJobs(ctx, jobs, opts.Parallelism), nil
+}
+
+// BuildJobs generates one job manifest per target without creating anything
+func (jm *JobManager) BuildJobs(jobName string, targets []JobTarget, opts JobOptions) ([]*batchv1.Job, error) {
+	if opts.RunID == "" {
+		opts.RunID = NewRunID()
+	}
+
+	jobs := make([]*batchv1.Job, 0, len(targets))
+	for _, target := range targets {
+		job, err := buildJob(jobName, target, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build job for %s: %v", target.name(), err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// SubmitJobs creates the given jobs using up to parallelism concurrent
+// requests, continuing past individual failures. One outcome is returned per
+// job, in the order of jobs. Once ctx is canceled no further jobs are created
+// and the remaining ones are reported as Canceled.
+func (jm *JobManager) SubmitJobs(ctx context.Context, jobs []*batchv1.Job, parallelism int) []JobCreation {
+	return jm.submitJobs(ctx, jobs, parallelism, metav1.CreateOptions{})
+}
+
+// DryRunJobs submits the given jobs like SubmitJobs with DryRun=All, so that
+// validation, admission webhooks and quota are checked without persisting them
+func (jm *JobManager) DryRunJobs(ctx context.Context, jobs []*batchv1.Job, parallelism int) []JobCreation {
+	return jm.submitJobs(ctx, jobs, parallelism, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+}
+
+// submitJobs creates jobs concurrently with the given create options
+func (jm *JobManager) submitJobs(ctx context.Context, jobs []*batchv1.Job, parallelism int, createOptions metav1.CreateOptions) []JobCreation {
+	if parallelism <= 0 {
+		parallelism = defaultJobParallelism
+	}
+	if parallelism > len(jobs) {
+		parallelism = len(jobs)
+	}
+
+	creations := make([]JobCreation, len(jobs))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				job := jobs[i]
+				creations[i] = JobCreation{
+					Node:      job.Annotations[NodeAnnotation],
+					TargetPod: job.Annotations[PodAnnotation],
+					Job:       job.Name,
+				}
+				err := ctx.Err()
+				if err == nil {
+					creations[i].Object, err = jm.clientset.BatchV1().Jobs(job.Namespace).Create(ctx, job, createOptions)
+				}
+				if err != nil {
+					creations[i].Object = nil
+					creations[i].Err = err
+					creations[i].Category = CategorizeError(err)
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return creations
+}
+
+// ActiveJobs returns the unfinished jobs created for the same task and
+// workload, keyed by target name (see JobTargetName)
+func (jm *JobManager) ActiveJobs(ctx context.Context, namespace, jobName string, workload WorkloadRef) (map[string]string, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		TaskLabel:   labelValue(jobName),
+		TargetLabel: WorkloadLabelValue(workload),
+	})
+	jobs, err := jm.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %v", err)
+	}
+
+	active := make(map[string]string)
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if _, finished := jobPhase(job); finished || job.DeletionTimestamp != nil {
+			continue
+		}
+		active[JobTargetName(job)] = job.Name
+	}
+	return active, nil
+}
+
+// buildJob generates the job for a single target
+func buildJob(jobName string, target JobTarget, opts JobOptions) (*batchv1.Job, error) {
+	command := opts.Command
+	if len(command) == 0 {
+		command = defaultJobCommand
+	}
+
+	name := JobInstanceName(jobName, opts.RunID, target.Target())
+	runLabels := jobLabels(jobName, target, opts)
+
+	annotations := map[string]string{
+		NodeAnnotation: target.Node,
+	}
+	if target.Pod != nil {
+		annotations[PodAnnotation] = target.Pod.Name
+	}
+
+	ttlSecondsAfterFinished := int32(300) // 5 minutes after completion
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   opts.Namespace,
+			Labels:      runLabels,
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      podLabels(name, runLabels),
+					Annotations: annotations,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Tolerations:   targetTolerations(opts.Tolerations, target),
+					Containers: []corev1.Container{
+						{
+							Name:    JobContainerName,
+							Image:   opts.Image,
+							Command: command,
 						},
 					},
 				},
 			},
-		}
+		},
+	}
+
+	if err := applyPlacement(&job.Spec.Template.Spec, target, opts.Placement); err != nil {
+		return nil, err
+	}
+	opts.Settings.apply(&job.Spec)
+	applyPodSettings(&job.Spec.Template.Spec, opts)
+
+	if target.Pod != nil {
+		job.Spec.Template.Spec.Containers[0].Env = targetPodEnv(target.Pod)
+	}
+
+	if opts.Host {
+		applyHostMode(&job.Spec.Template.Spec)
+	}
 
-		_, err := jm.clientset.BatchV1().Jobs(namespace).Create(context.TODO(), job, metav1.CreateOptions{})
+	if opts.Task != nil {
+		spec, err := opts.Task.Render(NewTaskData(target, opts))
 		if err != nil {
-			lastError = fmt.Errorf("failed to create job on node %s: %v", node, err)
+			return nil, err
+		}
+		if err := spec.MergeInto(&job.Spec.Template); err != nil {
+			return nil, err
+		}
+		if spec.Job != nil {
+			spec.Job.apply(&job.Spec)
+		}
+		// The job controller and runs rely on these labels; a task must not change them
+		if job.Spec.Template.Labels == nil {
+			job.Spec.Template.Labels = map[string]string{}
+		}
+		for key, value := range podLabels(name, runLabels) {
+			job.Spec.Template.Labels[key] = value
+		}
+	}
+
+	return job, nil
+}
+
+// targetTolerations returns the tolerations given for every job followed by those of the target
+func targetTolerations(tolerations []corev1.Toleration, target JobTarget) []corev1.Toleration {
+	if len(target.Tolerations) == 0 {
+		return tolerations
+	}
+	merged := make([]corev1.Toleration, 0, len(tolerations)+len(target.Tolerations))
+	merged = append(merged, tolerations...)
+	return append(merged, target.Tolerations...)
+}
+
+// applyPodSettings sets the resources, image pull settings, priority class and
+// service account given in opts on the generated pod spec
+func applyPodSettings(podSpec *corev1.PodSpec, opts JobOptions) {
+	container := &podSpec.Containers[0]
+	container.Resources = opts.Resources
+	container.ImagePullPolicy = opts.ImagePullPolicy
+
+	podSpec.PriorityClassName = opts.PriorityClassName
+	podSpec.ServiceAccountName = opts.ServiceAccountName
+	podSpec.ImagePullSecrets = opts.ImagePullSecrets
+}
+
+// jobLabels returns the labels identifying the run, node, target and task of a job
+func jobLabels(jobName string, target JobTarget, opts JobOptions) map[string]string {
+	set := map[string]string{
+		RunIDLabel:  opts.RunID,
+		NodeLabel:   labelValue(target.Node),
+		TargetLabel: WorkloadLabelValue(opts.Workload),
+		TaskLabel:   labelValue(jobName),
+	}
+	if opts.Version != "" {
+		set[VersionLabel] = labelValue(opts.Version)
+	}
+	return set
+}
+
+// podLabels returns the job labels plus the job-name label used by the job controller
+func podLabels(name string, base map[string]string) map[string]string {
+	merged := map[string]string{"job-name": name}
+	for key, value := range base {
+		merged[key] = value
+	}
+	return merged
+}
+
+// applyHostMode gives the job container access to the node: host PID and
+// network namespaces, a privileged security context and the root filesystem
+func applyHostMode(podSpec *corev1.PodSpec) {
+	privileged := true
+
+	podSpec.HostPID = true
+	podSpec.HostNetwork = true
+	podSpec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: "host-root",
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: "/"},
+		},
+	})
+
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		if container.Name != JobContainerName {
 			continue
 		}
+		container.SecurityContext = &corev1.SecurityContext{Privileged: &privileged}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "host-root",
+			MountPath: HostRootMountPath,
+			ReadOnly:  true,
+		})
+	}
+}
 
-		jobsCreated = append(jobsCreated, jobInstanceName)
+// Target identifies the target, as node or node/pod
+func (t JobTarget) Target() string {
+	if t.Pod == nil {
+		return t.Node
 	}
+	return TargetName(t.Node, t.Pod.Name)
+}
 
-	if len(jobsCreated) == 0 && lastError != nil {
-		return nil, lastError
+// name identifies the target in messages
+func (t JobTarget) name() string {
+	if t.Pod == nil {
+		return "node " + t.Node
 	}
+	return fmt.Sprintf("pod %s on node %s", t.Pod.Name, t.Node)
+}
 
-	return jobsCreated, nil
-}
\ No newline at end of file
+// targetPodEnv describes the target pod of a per-pod job as environment variables
+func targetPodEnv(pod *corev1.Pod) []corev1.EnvVar {
+	var containerIDs []string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.ContainerID != "" {
+			containerIDs = append(containerIDs, status.ContainerID)
+		}
+	}
+
+	return []corev1.EnvVar{
+		{Name: "TARGET_POD_NAME", Value: pod.Name},
+		{Name: "TARGET_POD_NAMESPACE", Value: pod.Namespace},
+		{Name: "TARGET_POD_UID", Value: string(pod.UID)},
+		{Name: "TARGET_POD_IP", Value: pod.Status.PodIP},
+		{Name: "TARGET_NODE_NAME", Value: pod.Spec.NodeName},
+		{Name: "TARGET_CONTAINER_IDS", Value: strings.Join(containerIDs, ",")},
+	}
+}
@@ -2,11 +2,19 @@ package k8s
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestJobManager_CreateJobOnNodes(t *testing.T) {
@@ -86,7 +94,7 @@ func TestJobManager_CreateJobOnNodes(t *testing.T) {
 			jm := &JobManager{clientset: clientset}
 
 			// Execute
-			jobs, err := jm.CreateJobOnNodes(tt.jobName, tt.nodes, tt.namespace, tt.image, tt.command, tt.tolerations)
+			jobs, err := jm.CreateJobOnNodes(context.TODO(), tt.jobName, tt.nodes, tt.namespace, tt.image, tt.command, tt.tolerations)
 
 			// Check error
 			if (err != nil) != tt.wantErr {
@@ -102,7 +110,7 @@ func TestJobManager_CreateJobOnNodes(t *testing.T) {
 			// Verify jobs were created with correct configuration
 			for i, node := range tt.nodes {
 				jobName := jobs[i]
-				
+
 				// Get the created job
 				job, err := clientset.BatchV1().Jobs(tt.namespace).Get(context.TODO(), jobName, metav1.GetOptions{})
 				if err != nil {
@@ -139,7 +147,7 @@ func TestJobManager_CreateJobOnNodes(t *testing.T) {
 				if len(expectedCmd) == 0 {
 					expectedCmd = []string{"echo", "Job running on node"}
 				}
-				
+
 				if len(job.Spec.Template.Spec.Containers) > 0 {
 					actualCmd := job.Spec.Template.Spec.Containers[0].Command
 					if len(actualCmd) != len(expectedCmd) {
@@ -152,6 +160,19 @@ func TestJobManager_CreateJobOnNodes(t *testing.T) {
 					t.Errorf("Expected job-name label %s, got %s", jobName, job.Spec.Template.Labels["job-name"])
 				}
 
+				// Verify node annotation
+				if job.Annotations[NodeAnnotation] != node {
+					t.Errorf("Expected node annotation %s, got %s", node, job.Annotations[NodeAnnotation])
+				}
+
+				// Verify labels; all jobs of one call share a run ID
+				if job.Labels[NodeLabel] != node || job.Labels[TaskLabel] != tt.jobName {
+					t.Errorf("Unexpected labels %v", job.Labels)
+				}
+				if runID := job.Labels[RunIDLabel]; runID == "" || job.Name != JobInstanceName(tt.jobName, runID, node) {
+					t.Errorf("Expected job name derived from run ID %q, got %s", runID, job.Name)
+				}
+
 				// Verify tolerations
 				if len(tt.tolerations) != len(job.Spec.Template.Spec.Tolerations) {
 					t.Errorf("Expected %d tolerations, got %d", len(tt.tolerations), len(job.Spec.Template.Spec.Tolerations))
@@ -159,4 +180,410 @@ func TestJobManager_CreateJobOnNodes(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestJobManager_CreateJobs(t *testing.T) {
+	task, err := ParseTask([]byte(`
+command: ["sh", "-c", 'echo {{.NodeName}} {{join .Pods ","}}']
+`))
+	if err != nil {
+		t.Fatalf("ParseTask() error = %v", err)
+	}
+
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "node2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-3", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-4", Namespace: "default"}},
+	}
+
+	clientset := fake.NewSimpleClientset()
+	jm := &JobManager{clientset: clientset}
+
+	creations, err := jm.CreateJobs(context.TODO(), "task", NodeTargets(pods), JobOptions{
+		Namespace: "jobs",
+		Image:     "busybox",
+		Workload:  WorkloadRef{Kind: KindDeployment, Name: "web"},
+		Task:      task,
+	})
+	if err != nil {
+		t.Fatalf("CreateJobs() error = %v", err)
+	}
+	jobs := CreatedJobs(creations)
+	if len(jobs) != 2 {
+		t.Fatalf("Expected 2 jobs, got %d", len(jobs))
+	}
+
+	expected := map[string]string{
+		"node1": "echo node1 web-1,web-3",
+		"node2": "echo node2 web-2",
+	}
+	for _, jobName := range jobs {
+		job, err := clientset.BatchV1().Jobs("jobs").Get(context.TODO(), jobName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get created job %s: %v", jobName, err)
+		}
+		node := job.Annotations[NodeAnnotation]
+		command := job.Spec.Template.Spec.Containers[0].Command
+		if len(command) != 3 || command[2] != expected[node] {
+			t.Errorf("Expected command %q on %s, got %v", expected[node], node, command)
+		}
+	}
+}
+
+func TestBuildJob_Host(t *testing.T) {
+	tests := []struct {
+		name string
+		host bool
+	}{
+		{name: "default job", host: false},
+		{name: "host job", host: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := buildJob("task-abc", JobTarget{Node: "node1"}, JobOptions{
+				Namespace: "default",
+				Image:     "busybox",
+				Host:      tt.host,
+			})
+			if err != nil {
+				t.Fatalf("buildJob() error = %v", err)
+			}
+
+			podSpec := job.Spec.Template.Spec
+			if podSpec.HostPID != tt.host || podSpec.HostNetwork != tt.host {
+				t.Errorf("Expected hostPID/hostNetwork %v, got %v/%v", tt.host, podSpec.HostPID, podSpec.HostNetwork)
+			}
+
+			container := podSpec.Containers[0]
+			privileged := container.SecurityContext != nil && container.SecurityContext.Privileged != nil && *container.SecurityContext.Privileged
+			if privileged != tt.host {
+				t.Errorf("Expected privileged %v, got %v", tt.host, privileged)
+			}
+
+			if !tt.host {
+				if len(podSpec.Volumes) != 0 || len(container.VolumeMounts) != 0 {
+					t.Errorf("Expected no host mounts, got %v", container.VolumeMounts)
+				}
+				return
+			}
+			if podSpec.DNSPolicy != corev1.DNSClusterFirstWithHostNet {
+				t.Errorf("Expected DNS policy %s, got %s", corev1.DNSClusterFirstWithHostNet, podSpec.DNSPolicy)
+			}
+			if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].HostPath == nil || podSpec.Volumes[0].HostPath.Path != "/" {
+				t.Fatalf("Expected host root volume, got %v", podSpec.Volumes)
+			}
+			if len(container.VolumeMounts) != 1 {
+				t.Fatalf("Expected 1 volume mount, got %d", len(container.VolumeMounts))
+			}
+			mount := container.VolumeMounts[0]
+			if mount.MountPath != HostRootMountPath || !mount.ReadOnly {
+				t.Errorf("Expected read-only mount at %s, got %+v", HostRootMountPath, mount)
+			}
+		})
+	}
+}
+
+func TestBuildJob_Settings(t *testing.T) {
+	backoffLimit := int32(0)
+	ttl := int32(60)
+	task, err := ParseTask([]byte("job:\n  ttlSecondsAfterFinished: 3600\n"))
+	if err != nil {
+		t.Fatalf("ParseTask() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		task    *Task
+		wantTTL int32
+	}{
+		{name: "flags", wantTTL: 60},
+		{name: "task file takes precedence", task: task, wantTTL: 3600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := buildJob("task-abc", JobTarget{Node: "node1"}, JobOptions{
+				Namespace: "default",
+				Image:     "busybox",
+				Task:      tt.task,
+				Settings:  JobSettings{TTLSecondsAfterFinished: &ttl, BackoffLimit: &backoffLimit},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+				},
+				ImagePullPolicy:    corev1.PullIfNotPresent,
+				PriorityClassName:  "low",
+				ServiceAccountName: "inspector",
+				ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "registry"}},
+			})
+			if err != nil {
+				t.Fatalf("buildJob() error = %v", err)
+			}
+
+			if *job.Spec.TTLSecondsAfterFinished != tt.wantTTL {
+				t.Errorf("Expected TTL %d, got %d", tt.wantTTL, *job.Spec.TTLSecondsAfterFinished)
+			}
+			if job.Spec.BackoffLimit == nil || *job.Spec.BackoffLimit != 0 {
+				t.Errorf("Expected backoff limit 0, got %v", job.Spec.BackoffLimit)
+			}
+			if job.Spec.ActiveDeadlineSeconds != nil {
+				t.Errorf("Expected no deadline, got %d", *job.Spec.ActiveDeadlineSeconds)
+			}
+
+			podSpec := job.Spec.Template.Spec
+			container := podSpec.Containers[0]
+			if cpu := container.Resources.Requests[corev1.ResourceCPU]; cpu.String() != "50m" || container.ImagePullPolicy != corev1.PullIfNotPresent {
+				t.Errorf("Unexpected container settings %+v", container)
+			}
+			if podSpec.PriorityClassName != "low" || podSpec.ServiceAccountName != "inspector" || len(podSpec.ImagePullSecrets) != 1 {
+				t.Errorf("Unexpected pod settings %+v", podSpec)
+			}
+		})
+	}
+}
+
+func TestPodTargets(t *testing.T) {
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-2"}, Spec: corev1.PodSpec{NodeName: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-1"}, Spec: corev1.PodSpec{NodeName: "node2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-3"}, Spec: corev1.PodSpec{NodeName: "node1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "web-4"}},
+	}
+
+	targets := PodTargets(pods)
+
+	expected := []string{"node1/web-2", "node1/web-3", "node2/web-1"}
+	if len(targets) != len(expected) {
+		t.Fatalf("Expected %d targets, got %d", len(expected), len(targets))
+	}
+	for i, target := range targets {
+		if got := TargetName(target.Node, target.Pod.Name); got != expected[i] {
+			t.Errorf("Expected target %s, got %s", expected[i], got)
+		}
+		if len(target.Pods) != 1 || target.Pods[0].Name != target.Pod.Name {
+			t.Errorf("Expected only pod %s in target, got %v", target.Pod.Name, target.Pods)
+		}
+	}
+}
+
+func TestBuildJob_PerPod(t *testing.T) {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "prod", UID: "uid-1"},
+		Spec:       corev1.PodSpec{NodeName: "node1"},
+		Status: corev1.PodStatus{
+			PodIP: "10.0.0.1",
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", ContainerID: "containerd://abc"},
+				{Name: "sidecar", ContainerID: "containerd://def"},
+			},
+		},
+	}
+
+	job, err := buildJob("task-abc", PodTargets([]corev1.Pod{pod})[0], JobOptions{Namespace: "default", Image: "busybox"})
+	if err != nil {
+		t.Fatalf("buildJob() error = %v", err)
+	}
+
+	if job.Annotations[PodAnnotation] != "web-1" || job.Spec.Template.Annotations[PodAnnotation] != "web-1" {
+		t.Errorf("Expected pod annotation web-1 on job and pod template, got %v / %v", job.Annotations, job.Spec.Template.Annotations)
+	}
+	if job.Spec.Template.Spec.NodeSelector["kubernetes.io/hostname"] != "node1" {
+		t.Errorf("Expected node selector node1, got %v", job.Spec.Template.Spec.NodeSelector)
+	}
+
+	env := make(map[string]string)
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	expected := map[string]string{
+		"TARGET_POD_NAME":      "web-1",
+		"TARGET_POD_NAMESPACE": "prod",
+		"TARGET_POD_UID":       "uid-1",
+		"TARGET_POD_IP":        "10.0.0.1",
+		"TARGET_NODE_NAME":     "node1",
+		"TARGET_CONTAINER_IDS": "containerd://abc,containerd://def",
+	}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("Expected %s=%s, got %q", name, value, env[name])
+		}
+	}
+}
+
+func TestJobManager_SubmitJobs(t *testing.T) {
+	tests := []struct {
+		name        string
+		nodes       int
+		parallelism int
+		failNode    string
+		wantMax     int
+	}{
+		{name: "bounded parallelism", nodes: 12, parallelism: 4, wantMax: 4},
+		{name: "default parallelism", nodes: 25, parallelism: 0, wantMax: defaultJobParallelism},
+		{name: "partial failure", nodes: 6, parallelism: 3, failNode: "node-03", wantMax: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			running, maxRunning := 0, 0
+
+			clientset := fake.NewSimpleClientset()
+			clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+
+				job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+				if job.Annotations[NodeAnnotation] == tt.failNode {
+					return true, nil, apierrors.NewForbidden(batchv1.Resource("jobs"), job.Name, fmt.Errorf("exceeded quota: jobs"))
+				}
+				return false, nil, nil
+			})
+			jm := &JobManager{clientset: clientset}
+
+			var targets []JobTarget
+			for i := 0; i < tt.nodes; i++ {
+				targets = append(targets, JobTarget{Node: fmt.Sprintf("node-%02d", i)})
+			}
+			jobs, err := jm.BuildJobs("task", targets, JobOptions{Namespace: "default", Image: "busybox"})
+			if err != nil {
+				t.Fatalf("BuildJobs() error = %v", err)
+			}
+
+			creations := jm.SubmitJobs(context.TODO(), jobs, tt.parallelism)
+			if len(creations) != len(jobs) {
+				t.Fatalf("Expected %d outcomes, got %d", len(jobs), len(creations))
+			}
+			for _, c := range creations {
+				failed := c.Node == tt.failNode
+				if c.Created() == failed {
+					t.Errorf("Expected created=%v for %s, got %v", !failed, c.Node, c.Created())
+				}
+				if failed && c.Category != ErrorCategoryQuotaExceeded {
+					t.Errorf("Expected category %s for %s, got %s", ErrorCategoryQuotaExceeded, c.Node, c.Category)
+				}
+			}
+			if failures := CreationFailures(creations); (tt.failNode != "") != (failures != nil) {
+				t.Errorf("Unexpected creation failures: %v", failures)
+			}
+			created := CreatedJobs(creations)
+
+			if maxRunning > tt.wantMax {
+				t.Errorf("Expected at most %d concurrent creates, got %d", tt.wantMax, maxRunning)
+			}
+
+			// Created names keep the order of the input jobs
+			var expected []string
+			for _, job := range jobs {
+				if job.Annotations[NodeAnnotation] != tt.failNode {
+					expected = append(expected, job.Name)
+				}
+			}
+			if len(created) != len(expected) {
+				t.Fatalf("Expected %d created jobs, got %d", len(expected), len(created))
+			}
+			for i := range expected {
+				if created[i] != expected[i] {
+					t.Errorf("Expected job %d to be %s, got %s", i, expected[i], created[i])
+				}
+			}
+		})
+	}
+}
+
+func TestJobManager_SubmitJobs_Canceled(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	jm := &JobManager{clientset: clientset}
+
+	jobs, err := jm.BuildJobs("task", []JobTarget{{Node: "node1"}, {Node: "node2"}}, JobOptions{Namespace: "default", Image: "busybox"})
+	if err != nil {
+		t.Fatalf("BuildJobs() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	creations := jm.SubmitJobs(ctx, jobs, 2)
+	for _, c := range creations {
+		if c.Created() || c.Category != ErrorCategoryCanceled {
+			t.Errorf("Expected %s to be canceled, got created=%v category=%s", c.Node, c.Created(), c.Category)
+		}
+	}
+
+	existing, _ := clientset.BatchV1().Jobs("default").List(context.TODO(), metav1.ListOptions{})
+	if len(existing.Items) != 0 {
+		t.Errorf("Expected no jobs to be created, got %d", len(existing.Items))
+	}
+}
+
+func TestJobManager_DryRunJobs(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job).DeepCopy()
+		if job.Annotations[NodeAnnotation] == "node2" {
+			return true, nil, apierrors.NewInvalid(batchv1.SchemeGroupVersion.WithKind("Job").GroupKind(), job.Name, nil)
+		}
+		// Simulate a mutating admission webhook
+		job.Labels["mutated"] = "true"
+		return true, job, nil
+	})
+	jm := &JobManager{clientset: clientset}
+
+	jobs, err := jm.BuildJobs("task", []JobTarget{{Node: "node1"}, {Node: "node2"}}, JobOptions{Namespace: "default", Image: "busybox"})
+	if err != nil {
+		t.Fatalf("BuildJobs() error = %v", err)
+	}
+
+	creations := jm.DryRunJobs(context.TODO(), jobs, 2)
+	if !creations[0].Created() || creations[0].Object == nil || creations[0].Object.Labels["mutated"] != "true" {
+		t.Errorf("Expected the job returned by the API server for node1, got %+v", creations[0])
+	}
+	if creations[1].Created() || creations[1].Object != nil || creations[1].Category != ErrorCategoryInvalid {
+		t.Errorf("Expected node2 to be rejected as %s, got %+v", ErrorCategoryInvalid, creations[1])
+	}
+}
+
+func TestJobManager_ActiveJobs(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	jm := &JobManager{clientset: clientset}
+	workload := WorkloadRef{Kind: KindDeployment, Name: "web"}
+
+	create := func(jobName, node string, workload WorkloadRef, finished bool) string {
+		jobs, err := jm.BuildJobs(jobName, []JobTarget{{Node: node}}, JobOptions{Namespace: "jobs", Image: "busybox", Workload: workload})
+		if err != nil {
+			t.Fatalf("BuildJobs() error = %v", err)
+		}
+		job := jobs[0]
+		if finished {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		}
+		if _, err := clientset.BatchV1().Jobs("jobs").Create(context.TODO(), job, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+		return job.Name
+	}
+
+	active := create("task", "node1", workload, false)
+	create("task", "node2", workload, true)
+	create("other-task", "node3", workload, false)
+	create("task", "node4", WorkloadRef{Kind: KindDeployment, Name: "api"}, false)
+
+	jobs, err := jm.ActiveJobs(context.TODO(), "jobs", "task", workload)
+	if err != nil {
+		t.Fatalf("ActiveJobs() error = %v", err)
+	}
+	if len(jobs) != 1 || jobs["node1"] != active {
+		t.Errorf("Expected only %s on node1, got %v", active, jobs)
+	}
+}
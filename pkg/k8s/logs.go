@@ -0,0 +1,139 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LogManagerInterface defines operations for reading job pod logs
+type LogManagerInterface interface {
+	WaitForJobPod(ctx context.Context, jobName, namespace string, timeout time.Duration) (*corev1.Pod, error)
+	StreamPodLogs(ctx context.Context, podName, namespace, container string, follow bool, w io.Writer) error
+}
+
+// LogManager manages log-related operations
+type LogManager struct {
+	clientset    kubernetes.Interface
+	pollInterval time.Duration
+}
+
+// NewLogManager creates a new log manager
+func NewLogManager(clientset kubernetes.Interface) LogManagerInterface {
+	return &LogManager{
+		clientset: clientset,
+	}
+}
+
+// WaitForJobPod waits until the job has a pod that has left the Pending phase,
+// so that its logs can be read. A zero timeout waits indefinitely.
+func (lm *LogManager) WaitForJobPod(ctx context.Context, jobName, namespace string, timeout time.Duration) (*corev1.Pod, error) {
+	ctx, cancel := waitContext(ctx, timeout)
+	defer cancel()
+
+	interval := lm.pollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+
+	var pod *corev1.Pod
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		latest, err := latestJobPod(ctx, lm.clientset, namespace, jobName)
+		if err != nil || latest == nil {
+			return false, nil
+		}
+		if latest.Status.Phase == corev1.PodPending || latest.Status.Phase == "" {
+			return false, nil
+		}
+		pod = latest
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a started pod for job %s: %v", jobName, err)
+	}
+
+	return pod, nil
+}
+
+// StreamPodLogs copies the logs of a pod container to w. When follow is set the
+// stream stays open until the container terminates.
+func (lm *LogManager) StreamPodLogs(ctx context.Context, podName, namespace, container string, follow bool, w io.Writer) error {
+	req := lm.clientset.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: container,
+		Follow:    follow,
+	})
+
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open log stream for pod %s: %v", podName, err)
+	}
+	defer stream.Close()
+
+	if _, err := io.Copy(w, stream); err != nil {
+		return fmt.Errorf("failed to read logs for pod %s: %v", podName, err)
+	}
+
+	return nil
+}
+
+// PrefixWriter prepends a prefix to every line written to it. Writers sharing
+// the same mutex never interleave partial lines, so several streams can be
+// written to one output concurrently.
+type PrefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+// NewPrefixWriter creates a writer that prefixes each line with prefix
+func NewPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *PrefixWriter {
+	return &PrefixWriter{
+		mu:     mu,
+		w:      w,
+		prefix: []byte(prefix),
+	}
+}
+
+// Write buffers p and emits every complete line with the prefix
+func (pw *PrefixWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := pw.writeLine(pw.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits any buffered partial line, terminated with a newline
+func (pw *PrefixWriter) Flush() error {
+	if len(pw.buf) == 0 {
+		return nil
+	}
+	line := append(pw.buf, '\n')
+	pw.buf = nil
+	return pw.writeLine(line)
+}
+
+func (pw *PrefixWriter) writeLine(line []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	if _, err := pw.w.Write(pw.prefix); err != nil {
+		return err
+	}
+	_, err := pw.w.Write(line)
+	return err
+}
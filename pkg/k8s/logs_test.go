@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLogManager_WaitForJobPod(t *testing.T) {
+	runningPod := newTestJobPod("job-a", "node1", 0)
+	runningPod.Status.Phase = corev1.PodRunning
+
+	pendingPod := newTestJobPod("job-b", "node2", 0)
+	pendingPod.Status.Phase = corev1.PodPending
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		jobName string
+		wantPod string
+		wantErr bool
+	}{
+		{
+			name:    "running pod is returned",
+			objects: []runtime.Object{runningPod},
+			jobName: "job-a",
+			wantPod: "job-a-abcde",
+			wantErr: false,
+		},
+		{
+			name:    "pending pod times out",
+			objects: []runtime.Object{pendingPod},
+			jobName: "job-b",
+			wantErr: true,
+		},
+		{
+			name:    "missing pod times out",
+			objects: nil,
+			jobName: "job-c",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.objects...)
+			lm := &LogManager{clientset: clientset, pollInterval: 10 * time.Millisecond}
+
+			pod, err := lm.WaitForJobPod(context.TODO(), tt.jobName, "default", 50*time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WaitForJobPod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if pod.Name != tt.wantPod {
+				t.Errorf("Expected pod %s, got %s", tt.wantPod, pod.Name)
+			}
+		})
+	}
+}
+
+func TestLogManager_StreamPodLogs(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default"}}
+	clientset := fake.NewSimpleClientset(pod)
+	lm := &LogManager{clientset: clientset}
+
+	var buf bytes.Buffer
+	if err := lm.StreamPodLogs(context.TODO(), "pod1", "default", "", false, &buf); err != nil {
+		t.Fatalf("StreamPodLogs() error = %v", err)
+	}
+
+	// The fake clientset always returns a fixed body
+	if buf.String() != "fake logs" {
+		t.Errorf("Expected fake logs, got %q", buf.String())
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		writes   []string
+		expected string
+	}{
+		{
+			name:     "single line",
+			writes:   []string{"hello\n"},
+			expected: "[node1] hello\n",
+		},
+		{
+			name:     "multiple lines in one write",
+			writes:   []string{"a\nb\n"},
+			expected: "[node1] a\n[node1] b\n",
+		},
+		{
+			name:     "line split across writes",
+			writes:   []string{"hel", "lo\nwor", "ld\n"},
+			expected: "[node1] hello\n[node1] world\n",
+		},
+		{
+			name:     "trailing partial line is flushed",
+			writes:   []string{"done"},
+			expected: "[node1] done\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			pw := NewPrefixWriter(&buf, &sync.Mutex{}, "[node1] ")
+			for _, w := range tt.writes {
+				if _, err := pw.Write([]byte(w)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := pw.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels set on every job and job pod so that runs can be found and correlated
+const (
+	// RunIDLabel identifies the run-job invocation that created the job
+	RunIDLabel = "deployment-inspector/run-id"
+	// NodeLabel is the node the job runs on
+	NodeLabel = "deployment-inspector/node"
+	// TargetLabel is the target workload in kind.name form
+	TargetLabel = "deployment-inspector/target"
+	// TaskLabel is the job name given to run-job
+	TaskLabel = "deployment-inspector/task"
+	// VersionLabel is the version of deployment-inspector that created the job
+	VersionLabel = "deployment-inspector/version"
+)
+
+// maxRunIDLength leaves room for the task name in generated job names
+const maxRunIDLength = 20
+
+// runIDLength is the length of generated run IDs
+const runIDLength = 8
+
+// invalidLabelChars matches characters that are not allowed in label values
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// NewRunID generates a random run ID
+func NewRunID() string {
+	return utilrand.String(runIDLength)
+}
+
+// ValidateRunID checks that a run ID can be used in job names and labels
+func ValidateRunID(runID string) error {
+	if errs := validation.IsDNS1123Label(runID); len(errs) > 0 {
+		return fmt.Errorf("invalid run ID %q: %s", runID, strings.Join(errs, ", "))
+	}
+	if len(runID) > maxRunIDLength {
+		return fmt.Errorf("invalid run ID %q: must be no more than %d characters", runID, maxRunIDLength)
+	}
+	return nil
+}
+
+// JobInstanceName returns the name of the job for a target within a run:
+// <jobName>-<runID>-<hash of target>. The job name is shortened so that the
+// result fits in a label value, which the job controller requires.
+func JobInstanceName(jobName, runID, target string) string {
+	suffix := fmt.Sprintf("-%s-%s", runID, shortHash(target))
+	prefix := jobName
+	if limit := validation.DNS1123LabelMaxLength - len(suffix); len(prefix) > limit {
+		prefix = prefix[:limit]
+	}
+	return strings.TrimRight(prefix, "-.") + suffix
+}
+
+// EphemeralContainerName returns the name of the debug container of a run:
+// <name>-<runID>. The name is shortened to fit a container name, and dots,
+// which container names do not allow, are replaced.
+func EphemeralContainerName(name, runID string) string {
+	suffix := "-" + runID
+	prefix := strings.ReplaceAll(name, ".", "-")
+	if limit := validation.DNS1123LabelMaxLength - len(suffix); len(prefix) > limit {
+		prefix = prefix[:limit]
+	}
+	return strings.TrimRight(prefix, "-") + suffix
+}
+
+// WorkloadLabelValue returns the target label value for a workload
+func WorkloadLabelValue(ref WorkloadRef) string {
+	if ref.Kind == KindSelector {
+		return labelValue("selector." + ref.Selector)
+	}
+	return labelValue(strings.ToLower(string(ref.Kind)) + "." + ref.Name)
+}
+
+// labelValue returns s if it is a valid label value. Otherwise invalid
+// characters are replaced and a hash of s is appended to keep it unique.
+func labelValue(s string) string {
+	if len(validation.IsValidLabelValue(s)) == 0 {
+		return s
+	}
+
+	hash := shortHash(s)
+	sanitized := invalidLabelChars.ReplaceAllString(s, "-")
+	if limit := validation.LabelValueMaxLength - len(hash) - 1; len(sanitized) > limit {
+		sanitized = sanitized[:limit]
+	}
+	sanitized = strings.Trim(sanitized, "-_.")
+	if sanitized == "" {
+		return hash
+	}
+	return sanitized + "-" + hash
+}
+
+// shortHash returns a short, stable hash of s
+func shortHash(s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestEphemeralContainerName(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobName string
+		want    string
+	}{
+		{name: "short name", jobName: "netcheck", want: "netcheck-abc123"},
+		{name: "dots are replaced", jobName: "net.check", want: "net-check-abc123"},
+		{name: "long name is truncated", jobName: strings.Repeat("a", 70), want: strings.Repeat("a", 56) + "-abc123"},
+		{name: "truncation drops trailing dashes", jobName: strings.Repeat("a", 55) + "-b", want: strings.Repeat("a", 55) + "-abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := EphemeralContainerName(tt.jobName, "abc123")
+			if name != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, name)
+			}
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				t.Errorf("Invalid container name %s: %v", name, errs)
+			}
+		})
+	}
+}
+
+func TestJobInstanceName(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobName string
+		runID   string
+		target  string
+		prefix  string
+	}{
+		{name: "short name", jobName: "cleanup", runID: "abc123", target: "node1", prefix: "cleanup-abc123-"},
+		{name: "per-pod target", jobName: "cleanup", runID: "abc123", target: "node1/web-1", prefix: "cleanup-abc123-"},
+		{name: "long name is truncated", jobName: strings.Repeat("a", 70), runID: "abc123", target: "node1", prefix: strings.Repeat("a", 47) + "-abc123-"},
+		{name: "truncation drops trailing dashes", jobName: strings.Repeat("a", 46) + "-b", runID: "abc123", target: "node1", prefix: strings.Repeat("a", 46) + "-abc123-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := JobInstanceName(tt.jobName, tt.runID, tt.target)
+			if !strings.HasPrefix(name, tt.prefix) {
+				t.Errorf("Expected prefix %s, got %s", tt.prefix, name)
+			}
+			if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+				t.Errorf("Invalid job name %s: %v", name, errs)
+			}
+			if again := JobInstanceName(tt.jobName, tt.runID, tt.target); again != name {
+				t.Errorf("Expected deterministic name %s, got %s", name, again)
+			}
+		})
+	}
+
+	if JobInstanceName("cleanup", "abc123", "node1") == JobInstanceName("cleanup", "abc123", "node2") {
+		t.Error("Expected different names for different targets")
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "valid value", value: "node1.example.com", expected: "node1.example.com"},
+		{name: "invalid characters", value: "app=web,tier!=db", expected: "app-web-tier--db-" + shortHash("app=web,tier!=db")},
+		{name: "too long", value: strings.Repeat("n", 70), expected: strings.Repeat("n", 54) + "-" + shortHash(strings.Repeat("n", 70))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := labelValue(tt.value)
+			if value != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, value)
+			}
+			if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+				t.Errorf("Invalid label value %s: %v", value, errs)
+			}
+		})
+	}
+}
+
+func TestWorkloadLabelValue(t *testing.T) {
+	if value := WorkloadLabelValue(WorkloadRef{Kind: KindStatefulSet, Name: "redis"}); value != "statefulset.redis" {
+		t.Errorf("Expected statefulset.redis, got %s", value)
+	}
+	value := WorkloadLabelValue(WorkloadRef{Kind: KindSelector, Selector: "app=web"})
+	if errs := validation.IsValidLabelValue(value); len(errs) > 0 || !strings.HasPrefix(value, "selector.app-web-") {
+		t.Errorf("Unexpected selector label value %s", value)
+	}
+}
+
+func TestValidateRunID(t *testing.T) {
+	tests := []struct {
+		runID       string
+		expectError bool
+	}{
+		{runID: NewRunID()},
+		{runID: "nightly-20240101"},
+		{runID: "Nightly", expectError: true},
+		{runID: "run_1", expectError: true},
+		{runID: strings.Repeat("a", 21), expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.runID, func(t *testing.T) {
+			if err := ValidateRunID(tt.runID); (err != nil) != tt.expectError {
+				t.Errorf("ValidateRunID() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
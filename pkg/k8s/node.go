@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodeManagerInterface defines operations on the nodes running target pods
+type NodeManagerInterface interface {
+	GetNodes(ctx context.Context, names []string) (map[string]*corev1.Node, error)
+}
+
+// NodeManager looks up nodes
+type NodeManager struct {
+	clientset kubernetes.Interface
+}
+
+// NewNodeManager creates a new node manager
+func NewNodeManager(clientset kubernetes.Interface) NodeManagerInterface {
+	return &NodeManager{
+		clientset: clientset,
+	}
+}
+
+// GetNodes returns the named nodes keyed by name. Nodes that do not exist are
+// missing from the result.
+func (nm *NodeManager) GetNodes(ctx context.Context, names []string) (map[string]*corev1.Node, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	nodes, err := nm.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	found := make(map[string]*corev1.Node, len(names))
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if wanted[node.Name] {
+			found[node.Name] = node
+		}
+	}
+	return found, nil
+}
+
+// TargetNodes returns the distinct nodes of the targets in order
+func TargetNodes(targets []JobTarget) []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, target := range targets {
+		if !seen[target.Node] {
+			seen[target.Node] = true
+			nodes = append(nodes, target.Node)
+		}
+	}
+	return nodes
+}
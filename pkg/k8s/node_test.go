@@ -0,0 +1,33 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeManager_GetNodes(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3"}},
+	)
+
+	nodes, err := NewNodeManager(clientset).GetNodes(context.TODO(), []string{"node1", "node3", "missing"})
+	if err != nil {
+		t.Fatalf("GetNodes() error = %v", err)
+	}
+	if len(nodes) != 2 || nodes["node1"] == nil || nodes["node3"] == nil {
+		t.Errorf("Expected node1 and node3, got %v", nodes)
+	}
+}
+
+func TestTargetNodes(t *testing.T) {
+	nodes := TargetNodes([]JobTarget{{Node: "node2"}, {Node: "node1"}, {Node: "node2"}})
+	if len(nodes) != 2 || nodes[0] != "node2" || nodes[1] != "node1" {
+		t.Errorf("Expected [node2 node1], got %v", nodes)
+	}
+}
@@ -0,0 +1,107 @@
+package k8s
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Node role labels of control-plane nodes; "master" is set by older clusters
+const (
+	controlPlaneRoleLabel = "node-role.kubernetes.io/control-plane"
+	masterRoleLabel       = "node-role.kubernetes.io/master"
+)
+
+// NodeFilter selects the target nodes jobs are created on
+type NodeFilter struct {
+	// ExcludeCordoned excludes nodes marked unschedulable
+	ExcludeCordoned bool
+	// ReadyOnly excludes nodes whose Ready condition is not True
+	ReadyOnly bool
+	// Selector excludes nodes whose labels do not match; nil matches every node
+	Selector labels.Selector
+	// ExcludeControlPlane excludes nodes with a control-plane or master role label
+	ExcludeControlPlane bool
+}
+
+// NodeExclusion records why a target node was excluded
+type NodeExclusion struct {
+	Node   string
+	Reason string
+}
+
+// ParseNodeSelector parses a label selector such as zone=us-east-1a. An empty
+// selector returns nil.
+func ParseNodeSelector(value string) (labels.Selector, error) {
+	if value == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selector %q: %v", value, err)
+	}
+	return selector, nil
+}
+
+// IsEmpty reports whether the filter keeps every node
+func (f NodeFilter) IsEmpty() bool {
+	return !f.ExcludeCordoned && !f.ReadyOnly && f.Selector == nil && !f.ExcludeControlPlane
+}
+
+// exclusionReason returns why node is excluded, or "" if it is kept
+func (f NodeFilter) exclusionReason(node *corev1.Node) string {
+	switch {
+	case f.ExcludeCordoned && node.Spec.Unschedulable:
+		return "node is cordoned"
+	case f.ReadyOnly && !nodeReady(node):
+		return "node is not ready"
+	case f.ExcludeControlPlane && isControlPlane(node):
+		return "control-plane node"
+	case f.Selector != nil && !f.Selector.Matches(labels.Set(node.Labels)):
+		return fmt.Sprintf("labels do not match %s", f.Selector)
+	}
+	return ""
+}
+
+// FilterTargets returns the targets whose node passes filter and the excluded
+// nodes in the order of the targets. Nodes missing from nodes are excluded.
+func FilterTargets(targets []JobTarget, nodes map[string]*corev1.Node, filter NodeFilter) ([]JobTarget, []NodeExclusion) {
+	reasons := make(map[string]string)
+	var exclusions []NodeExclusion
+	for _, name := range TargetNodes(targets) {
+		reason := "node not found"
+		if node, ok := nodes[name]; ok {
+			reason = filter.exclusionReason(node)
+		}
+		if reason != "" {
+			reasons[name] = reason
+			exclusions = append(exclusions, NodeExclusion{Node: name, Reason: reason})
+		}
+	}
+
+	remaining := make([]JobTarget, 0, len(targets))
+	for _, target := range targets {
+		if reasons[target.Node] == "" {
+			remaining = append(remaining, target)
+		}
+	}
+	return remaining, exclusions
+}
+
+// nodeReady reports whether the Ready condition of node is True
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isControlPlane reports whether node has a control-plane role label
+func isControlPlane(node *corev1.Node) bool {
+	_, controlPlane := node.Labels[controlPlaneRoleLabel]
+	_, master := node.Labels[masterRoleLabel]
+	return controlPlane || master
+}
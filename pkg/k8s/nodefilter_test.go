@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseNodeSelector(t *testing.T) {
+	if selector, err := ParseNodeSelector(""); err != nil || selector != nil {
+		t.Errorf("ParseNodeSelector(\"\") = %v, %v, want nil, nil", selector, err)
+	}
+	if _, err := ParseNodeSelector("zone=us-east-1a,disktype in (ssd)"); err != nil {
+		t.Errorf("ParseNodeSelector() error = %v", err)
+	}
+	if _, err := ParseNodeSelector("zone in (a"); err == nil {
+		t.Error("Expected an error for an invalid selector")
+	}
+}
+
+func TestFilterTargets(t *testing.T) {
+	ready := corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}}
+	notReady := corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}}}
+	nodes := map[string]*corev1.Node{
+		"worker-a":  {ObjectMeta: metav1.ObjectMeta{Name: "worker-a", Labels: map[string]string{"zone": "a"}}, Status: ready},
+		"worker-b":  {ObjectMeta: metav1.ObjectMeta{Name: "worker-b", Labels: map[string]string{"zone": "b"}}, Status: ready},
+		"cordoned":  {ObjectMeta: metav1.ObjectMeta{Name: "cordoned", Labels: map[string]string{"zone": "a"}}, Spec: corev1.NodeSpec{Unschedulable: true}, Status: ready},
+		"not-ready": {ObjectMeta: metav1.ObjectMeta{Name: "not-ready", Labels: map[string]string{"zone": "a"}}, Status: notReady},
+		"master":    {ObjectMeta: metav1.ObjectMeta{Name: "master", Labels: map[string]string{"zone": "a", masterRoleLabel: ""}}, Status: ready},
+	}
+	targets := []JobTarget{{Node: "worker-a"}, {Node: "worker-b"}, {Node: "cordoned"}, {Node: "not-ready"}, {Node: "master"}, {Node: "gone"}, {Node: "worker-a"}}
+	zoneA, err := ParseNodeSelector("zone=a")
+	if err != nil {
+		t.Fatalf("ParseNodeSelector() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		filter        NodeFilter
+		wantNodes     []string
+		wantExclusion []NodeExclusion
+	}{
+		{
+			name:          "empty filter",
+			wantNodes:     []string{"worker-a", "worker-b", "cordoned", "not-ready", "master", "worker-a"},
+			wantExclusion: []NodeExclusion{{Node: "gone", Reason: "node not found"}},
+		},
+		{
+			name:      "every filter",
+			filter:    NodeFilter{ExcludeCordoned: true, ReadyOnly: true, ExcludeControlPlane: true, Selector: zoneA},
+			wantNodes: []string{"worker-a", "worker-a"},
+			wantExclusion: []NodeExclusion{
+				{Node: "worker-b", Reason: "labels do not match zone=a"},
+				{Node: "cordoned", Reason: "node is cordoned"},
+				{Node: "not-ready", Reason: "node is not ready"},
+				{Node: "master", Reason: "control-plane node"},
+				{Node: "gone", Reason: "node not found"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, exclusions := FilterTargets(targets, nodes, tt.filter)
+			var gotNodes []string
+			for _, target := range remaining {
+				gotNodes = append(gotNodes, target.Node)
+			}
+			if !reflect.DeepEqual(gotNodes, tt.wantNodes) {
+				t.Errorf("Expected targets %v, got %v", tt.wantNodes, gotNodes)
+			}
+			if !reflect.DeepEqual(exclusions, tt.wantExclusion) {
+				t.Errorf("Expected exclusions %+v, got %+v", tt.wantExclusion, exclusions)
+			}
+		})
+	}
+}
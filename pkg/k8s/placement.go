@@ -0,0 +1,72 @@
+package k8s
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Placement is how a job pod is pinned to its target node
+type Placement string
+
+const (
+	// PlacementHostnameLabel selects the node by its kubernetes.io/hostname label
+	PlacementHostnameLabel Placement = "hostname-label"
+	// PlacementNodeNameAffinity requires the node's metadata.name through node affinity
+	PlacementNodeNameAffinity Placement = "node-name-affinity"
+	// PlacementNodeName binds the pod to the node with spec.nodeName, bypassing the scheduler
+	PlacementNodeName Placement = "node-name"
+)
+
+// ParsePlacement parses a placement strategy name
+func ParsePlacement(value string) (Placement, error) {
+	switch placement := Placement(value); placement {
+	case PlacementHostnameLabel, PlacementNodeNameAffinity, PlacementNodeName:
+		return placement, nil
+	default:
+		return "", fmt.Errorf("invalid placement %q (expected %s, %s or %s)", value, PlacementHostnameLabel, PlacementNodeNameAffinity, PlacementNodeName)
+	}
+}
+
+// SetHostnames records the kubernetes.io/hostname label of each target's node,
+// which may differ from the node name (e.g. on EKS). Targets whose node is
+// missing or unlabeled keep using the node name.
+func SetHostnames(targets []JobTarget, nodes map[string]*corev1.Node) {
+	for i := range targets {
+		if node, ok := nodes[targets[i].Node]; ok {
+			targets[i].Hostname = node.Labels[corev1.LabelHostname]
+		}
+	}
+}
+
+// applyPlacement pins the pod to the target's node using the given strategy.
+// An empty placement selects the hostname label.
+func applyPlacement(podSpec *corev1.PodSpec, target JobTarget, placement Placement) error {
+	switch placement {
+	case "", PlacementHostnameLabel:
+		hostname := target.Hostname
+		if hostname == "" {
+			hostname = target.Node
+		}
+		podSpec.NodeSelector = map[string]string{corev1.LabelHostname: hostname}
+	case PlacementNodeNameAffinity:
+		podSpec.Affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchFields: []corev1.NodeSelectorRequirement{{
+							Key:      "metadata.name",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{target.Node},
+						}},
+					}},
+				},
+			},
+		}
+	case PlacementNodeName:
+		podSpec.NodeName = target.Node
+	default:
+		return fmt.Errorf("invalid placement %q", placement)
+	}
+	return nil
+}
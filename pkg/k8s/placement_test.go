@@ -0,0 +1,82 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePlacement(t *testing.T) {
+	for _, value := range []string{"hostname-label", "node-name-affinity", "node-name"} {
+		if _, err := ParsePlacement(value); err != nil {
+			t.Errorf("ParsePlacement(%q) error = %v", value, err)
+		}
+	}
+	if _, err := ParsePlacement("hostname"); err == nil {
+		t.Error("Expected an error for an invalid placement")
+	}
+}
+
+func TestBuildJob_Placement(t *testing.T) {
+	tests := []struct {
+		name         string
+		placement    Placement
+		hostname     string
+		wantSelector string
+		wantAffinity bool
+		wantNodeName string
+	}{
+		{name: "default", wantSelector: "node1"},
+		{name: "hostname label differs from node name", placement: PlacementHostnameLabel, hostname: "ip-10-0-0-1", wantSelector: "ip-10-0-0-1"},
+		{name: "node name affinity", placement: PlacementNodeNameAffinity, wantAffinity: true},
+		{name: "node name binding", placement: PlacementNodeName, wantNodeName: "node1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, err := buildJob("task-abc", JobTarget{Node: "node1", Hostname: tt.hostname}, JobOptions{
+				Namespace: "default",
+				Image:     "busybox",
+				Placement: tt.placement,
+			})
+			if err != nil {
+				t.Fatalf("buildJob() error = %v", err)
+			}
+
+			podSpec := job.Spec.Template.Spec
+			if got := podSpec.NodeSelector[corev1.LabelHostname]; got != tt.wantSelector {
+				t.Errorf("Expected hostname selector %q, got %q", tt.wantSelector, got)
+			}
+			if podSpec.NodeName != tt.wantNodeName {
+				t.Errorf("Expected node name %q, got %q", tt.wantNodeName, podSpec.NodeName)
+			}
+			if (podSpec.Affinity != nil) != tt.wantAffinity {
+				t.Fatalf("Expected affinity %v, got %+v", tt.wantAffinity, podSpec.Affinity)
+			}
+			if tt.wantAffinity {
+				requirement := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchFields[0]
+				if requirement.Key != "metadata.name" || len(requirement.Values) != 1 || requirement.Values[0] != "node1" {
+					t.Errorf("Unexpected node affinity %+v", requirement)
+				}
+			}
+		})
+	}
+}
+
+func TestSetHostnames(t *testing.T) {
+	targets := []JobTarget{{Node: "node1"}, {Node: "node2"}, {Node: "node3"}}
+	nodes := map[string]*corev1.Node{
+		"node1": {ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{corev1.LabelHostname: "ip-10-0-0-1"}}},
+		"node2": {ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+	}
+
+	SetHostnames(targets, nodes)
+
+	expected := []string{"ip-10-0-0-1", "", ""}
+	for i, target := range targets {
+		if target.Hostname != expected[i] {
+			t.Errorf("Expected hostname %q for %s, got %q", expected[i], target.Node, target.Hostname)
+		}
+	}
+}
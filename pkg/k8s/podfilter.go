@@ -0,0 +1,176 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodFilter selects the target pods before their nodes are chosen
+type PodFilter struct {
+	// Phases keeps only pods in one of these phases; empty keeps every phase
+	Phases []corev1.PodPhase
+	// ReadyOnly keeps only pods whose Ready condition is True
+	ReadyOnly bool
+	// ExcludeTerminating drops pods that are being deleted
+	ExcludeTerminating bool
+	// Selector keeps only pods whose labels match; nil matches every pod
+	Selector labels.Selector
+	// Revision keeps only pods of this revision (see CurrentRevision); nil keeps every revision
+	Revision *PodRevision
+}
+
+// PodRevision identifies the pods of one revision of a workload by a label
+type PodRevision struct {
+	Label string
+	Hash  string
+}
+
+// PodExclusion records why a pod was dropped from the targets
+type PodExclusion struct {
+	Pod    string
+	Reason string
+}
+
+// ParsePodPhases parses pod phase names such as running or Pending
+func ParsePodPhases(values []string) ([]corev1.PodPhase, error) {
+	known := []corev1.PodPhase{corev1.PodPending, corev1.PodRunning, corev1.PodSucceeded, corev1.PodFailed, corev1.PodUnknown}
+	phases := make([]corev1.PodPhase, 0, len(values))
+	for _, value := range values {
+		found := false
+		for _, phase := range known {
+			if strings.EqualFold(value, string(phase)) {
+				phases = append(phases, phase)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid pod phase %q (expected Pending, Running, Succeeded, Failed or Unknown)", value)
+		}
+	}
+	return phases, nil
+}
+
+// IsEmpty reports whether the filter keeps every pod
+func (f PodFilter) IsEmpty() bool {
+	return len(f.Phases) == 0 && !f.ReadyOnly && !f.ExcludeTerminating && f.Selector == nil && f.Revision == nil
+}
+
+// exclusionReason returns why pod is dropped, or "" if it is kept
+func (f PodFilter) exclusionReason(pod *corev1.Pod) string {
+	switch {
+	case len(f.Phases) > 0 && !hasPhase(f.Phases, pod.Status.Phase):
+		return fmt.Sprintf("phase is %s", pod.Status.Phase)
+	case f.ExcludeTerminating && pod.DeletionTimestamp != nil:
+		return "pod is terminating"
+	case f.ReadyOnly && !podReady(pod):
+		return "pod is not ready"
+	case f.Revision != nil && pod.Labels[f.Revision.Label] != f.Revision.Hash:
+		return fmt.Sprintf("not the current revision (%s=%s)", f.Revision.Label, f.Revision.Hash)
+	case f.Selector != nil && !f.Selector.Matches(labels.Set(pod.Labels)):
+		return fmt.Sprintf("labels do not match %s", f.Selector)
+	}
+	return ""
+}
+
+// FilterPods returns the pods that pass filter and the dropped pods, in order
+func FilterPods(pods []corev1.Pod, filter PodFilter) ([]corev1.Pod, []PodExclusion) {
+	kept := make([]corev1.Pod, 0, len(pods))
+	var exclusions []PodExclusion
+	for i := range pods {
+		if reason := filter.exclusionReason(&pods[i]); reason != "" {
+			exclusions = append(exclusions, PodExclusion{Pod: pods[i].Name, Reason: reason})
+			continue
+		}
+		kept = append(kept, pods[i])
+	}
+	return kept, exclusions
+}
+
+// CurrentRevision returns the revision of the pods created from the newest
+// template of a Deployment (the pod-template-hash of its newest ReplicaSet),
+// StatefulSet or DaemonSet (their controller-revision-hash)
+func (wr *WorkloadResolver) CurrentRevision(ctx context.Context, ref WorkloadRef, namespace string) (*PodRevision, error) {
+	switch ref.Kind {
+	case KindDeployment:
+		replicaSets, err := NewDeploymentManager(wr.clientset).GetReplicaSets(ctx, ref.Name, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if len(replicaSets) == 0 {
+			return nil, fmt.Errorf("%s has no replicasets", ref)
+		}
+		newest := replicaSets[len(replicaSets)-1]
+		return &PodRevision{Label: appsv1.DefaultDeploymentUniqueLabelKey, Hash: newest.Labels[appsv1.DefaultDeploymentUniqueLabelKey]}, nil
+	case KindStatefulSet:
+		sts, err := wr.clientset.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s: %v", ref.Name, err)
+		}
+		if sts.Status.UpdateRevision == "" {
+			return nil, fmt.Errorf("%s has no update revision yet", ref)
+		}
+		return &PodRevision{Label: appsv1.ControllerRevisionHashLabelKey, Hash: sts.Status.UpdateRevision}, nil
+	case KindDaemonSet:
+		return wr.daemonSetRevision(ctx, ref, namespace)
+	default:
+		return nil, fmt.Errorf("the current revision of %s cannot be determined (expected a deployment, statefulset or daemonset)", ref)
+	}
+}
+
+// daemonSetRevision returns the revision of the newest ControllerRevision of a DaemonSet
+func (wr *WorkloadResolver) daemonSetRevision(ctx context.Context, ref WorkloadRef, namespace string) (*PodRevision, error) {
+	ds, err := wr.clientset.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daemonset %s: %v", ref.Name, err)
+	}
+	if ds.Spec.Selector == nil {
+		return nil, fmt.Errorf("%s has no selector", ref)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on %s: %v", ref, err)
+	}
+
+	revisions, err := wr.clientset.AppsV1().ControllerRevisions(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list controllerrevisions: %v", err)
+	}
+	var newest *appsv1.ControllerRevision
+	for i := range revisions.Items {
+		revision := &revisions.Items[i]
+		if metav1.IsControlledBy(revision, ds) && (newest == nil || revision.Revision > newest.Revision) {
+			newest = revision
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("%s has no controllerrevisions", ref)
+	}
+	return &PodRevision{Label: appsv1.ControllerRevisionHashLabelKey, Hash: newest.Labels[appsv1.ControllerRevisionHashLabelKey]}, nil
+}
+
+// hasPhase reports whether phase is one of phases
+func hasPhase(phases []corev1.PodPhase, phase corev1.PodPhase) bool {
+	for _, p := range phases {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// podReady reports whether the Ready condition of pod is True
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParsePodPhases(t *testing.T) {
+	phases, err := ParsePodPhases([]string{"running", "Pending"})
+	if err != nil {
+		t.Fatalf("ParsePodPhases() error = %v", err)
+	}
+	if !reflect.DeepEqual(phases, []corev1.PodPhase{corev1.PodRunning, corev1.PodPending}) {
+		t.Errorf("Unexpected phases %v", phases)
+	}
+	if _, err := ParsePodPhases([]string{"Terminating"}); err == nil {
+		t.Error("Expected an error for an invalid phase")
+	}
+}
+
+func TestFilterPods(t *testing.T) {
+	deleted := metav1.NewTime(time.Now())
+	readyCondition := []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	newPod := func(name, hash string, phase corev1.PodPhase, ready bool) corev1.Pod {
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+		if ready {
+			pod.Status.Conditions = readyCondition
+		}
+		return pod
+	}
+	terminating := newPod("web-new-3", "new", corev1.PodRunning, true)
+	terminating.DeletionTimestamp = &deleted
+	canary := newPod("web-new-4", "new", corev1.PodRunning, true)
+	canary.Labels["track"] = "canary"
+	pods := []corev1.Pod{
+		newPod("web-new-1", "new", corev1.PodRunning, true),
+		newPod("web-new-2", "new", corev1.PodRunning, false),
+		terminating,
+		canary,
+		newPod("web-old-1", "old", corev1.PodRunning, true),
+		newPod("web-old-2", "old", corev1.PodFailed, false),
+	}
+	stable, err := labels.Parse("track!=canary")
+	if err != nil {
+		t.Fatalf("labels.Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		filter        PodFilter
+		wantPods      []string
+		wantExclusion []PodExclusion
+	}{
+		{
+			name:     "empty filter",
+			wantPods: []string{"web-new-1", "web-new-2", "web-new-3", "web-new-4", "web-old-1", "web-old-2"},
+		},
+		{
+			name:          "phase",
+			filter:        PodFilter{Phases: []corev1.PodPhase{corev1.PodRunning}},
+			wantPods:      []string{"web-new-1", "web-new-2", "web-new-3", "web-new-4", "web-old-1"},
+			wantExclusion: []PodExclusion{{Pod: "web-old-2", Reason: "phase is Failed"}},
+		},
+		{
+			name: "every filter",
+			filter: PodFilter{
+				ReadyOnly:          true,
+				ExcludeTerminating: true,
+				Selector:           stable,
+				Revision:           &PodRevision{Label: appsv1.DefaultDeploymentUniqueLabelKey, Hash: "new"},
+			},
+			wantPods: []string{"web-new-1"},
+			wantExclusion: []PodExclusion{
+				{Pod: "web-new-2", Reason: "pod is not ready"},
+				{Pod: "web-new-3", Reason: "pod is terminating"},
+				{Pod: "web-new-4", Reason: "labels do not match track!=canary"},
+				{Pod: "web-old-1", Reason: "not the current revision (pod-template-hash=new)"},
+				{Pod: "web-old-2", Reason: "pod is not ready"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, exclusions := FilterPods(pods, tt.filter)
+			var names []string
+			for _, pod := range kept {
+				names = append(names, pod.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantPods) {
+				t.Errorf("Expected pods %v, got %v", tt.wantPods, names)
+			}
+			if !reflect.DeepEqual(exclusions, tt.wantExclusion) {
+				t.Errorf("Expected exclusions %+v, got %+v", tt.wantExclusion, exclusions)
+			}
+		})
+	}
+}
+
+func TestWorkloadResolver_CurrentRevision(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "deploy-uid"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+	newReplicaSet := func(name, hash, revision string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				UID:             types.UID("uid-" + name),
+				Labels:          map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+				Annotations:     map[string]string{RevisionAnnotation: revision},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+			},
+		}
+	}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default"},
+		Status:     appsv1.StatefulSetStatus{CurrentRevision: "redis-1", UpdateRevision: "redis-2"},
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "fluentd", Namespace: "default", UID: "ds-uid"},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "fluentd"}},
+		},
+	}
+	newControllerRevision := func(name, hash string, revision int64) *appsv1.ControllerRevision {
+		return &appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				Labels:          map[string]string{"app": "fluentd", appsv1.ControllerRevisionHashLabelKey: hash},
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ds, appsv1.SchemeGroupVersion.WithKind("DaemonSet"))},
+			},
+			Revision: revision,
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		deployment,
+		// Revision 10 sorts after revision 9 numerically, not lexically
+		newReplicaSet("web-aaa", "aaa", "9"),
+		newReplicaSet("web-bbb", "bbb", "10"),
+		sts,
+		ds,
+		newControllerRevision("fluentd-1", "c1", 1),
+		newControllerRevision("fluentd-2", "c2", 2),
+	)
+	resolver := NewWorkloadResolver(clientset)
+
+	tests := []struct {
+		ref     WorkloadRef
+		want    *PodRevision
+		wantErr bool
+	}{
+		{ref: WorkloadRef{Kind: KindDeployment, Name: "web"}, want: &PodRevision{Label: appsv1.DefaultDeploymentUniqueLabelKey, Hash: "bbb"}},
+		{ref: WorkloadRef{Kind: KindStatefulSet, Name: "redis"}, want: &PodRevision{Label: appsv1.ControllerRevisionHashLabelKey, Hash: "redis-2"}},
+		{ref: WorkloadRef{Kind: KindDaemonSet, Name: "fluentd"}, want: &PodRevision{Label: appsv1.ControllerRevisionHashLabelKey, Hash: "c2"}},
+		{ref: WorkloadRef{Kind: KindSelector, Selector: "app=web"}, wantErr: true},
+		{ref: WorkloadRef{Kind: KindDeployment, Name: "missing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref.String(), func(t *testing.T) {
+			revision, err := resolver.CurrentRevision(context.TODO(), tt.ref, "default")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CurrentRevision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(revision, tt.want) {
+				t.Errorf("Expected revision %+v, got %+v", tt.want, revision)
+			}
+		})
+	}
+}
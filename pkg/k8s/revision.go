@@ -0,0 +1,148 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// RevisionGroup describes the pods of one ReplicaSet of a deployment
+type RevisionGroup struct {
+	ReplicaSet string
+	Revision   int64
+	// Hash is the pod-template-hash of the ReplicaSet
+	Hash string
+	// Current is set for the ReplicaSet of the newest revision
+	Current bool
+	// Images are the container images of the ReplicaSet's pod template
+	Images []string
+	Pods   []corev1.Pod
+	// Nodes are the distinct nodes running the pods, sorted by name
+	Nodes []string
+}
+
+// GroupByRevision groups pods by the ReplicaSet controlling them, newest
+// revision first. replicaSets must be sorted oldest first (see GetReplicaSets).
+// ReplicaSets without pods are omitted, except for the newest one.
+func GroupByRevision(replicaSets []appsv1.ReplicaSet, pods []corev1.Pod) []RevisionGroup {
+	podsByOwner := make(map[types.UID][]corev1.Pod)
+	for _, pod := range pods {
+		if controllerRef := metav1.GetControllerOf(&pod); controllerRef != nil {
+			podsByOwner[controllerRef.UID] = append(podsByOwner[controllerRef.UID], pod)
+		}
+	}
+
+	var groups []RevisionGroup
+	for i := len(replicaSets) - 1; i >= 0; i-- {
+		rs := &replicaSets[i]
+		current := i == len(replicaSets)-1
+		rsPods := podsByOwner[rs.UID]
+		if len(rsPods) == 0 && !current {
+			continue
+		}
+		groups = append(groups, RevisionGroup{
+			ReplicaSet: rs.Name,
+			Revision:   ReplicaSetRevision(rs),
+			Hash:       rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey],
+			Current:    current,
+			Images:     templateImages(&rs.Spec.Template.Spec),
+			Pods:       rsPods,
+			Nodes:      podNodes(rsPods),
+		})
+	}
+	return groups
+}
+
+// RevisionSelector selects deployment revisions for run-job --revision
+type RevisionSelector struct {
+	// Value is the selector as given: a revision number, "new" or "old"
+	Value string
+	// Number is the selected revision when Value is a number
+	Number int64
+}
+
+// Revision selector values other than a revision number
+const (
+	// RevisionNew selects the newest revision of a deployment
+	RevisionNew = "new"
+	// RevisionOld selects every revision but the newest
+	RevisionOld = "old"
+)
+
+// ParseRevisionSelector parses a revision number, new or old
+func ParseRevisionSelector(value string) (*RevisionSelector, error) {
+	value = strings.ToLower(value)
+	if value == RevisionNew || value == RevisionOld {
+		return &RevisionSelector{Value: value}, nil
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || number < 1 {
+		return nil, fmt.Errorf("invalid revision %q (expected a revision number, %s or %s)", value, RevisionNew, RevisionOld)
+	}
+	return &RevisionSelector{Value: value, Number: number}, nil
+}
+
+// matches reports whether a revision group is selected
+func (s RevisionSelector) matches(group RevisionGroup) bool {
+	switch s.Value {
+	case RevisionNew:
+		return group.Current
+	case RevisionOld:
+		return !group.Current
+	default:
+		return group.Revision == s.Number
+	}
+}
+
+// SelectRevisionPods keeps the pods of the deployment revisions matching
+// selector. replicaSets must be sorted oldest first (see GetReplicaSets).
+func SelectRevisionPods(replicaSets []appsv1.ReplicaSet, pods []corev1.Pod, selector RevisionSelector) ([]corev1.Pod, []PodExclusion) {
+	selected := make(map[string]bool)
+	for _, group := range GroupByRevision(replicaSets, pods) {
+		if selector.matches(group) {
+			for _, pod := range group.Pods {
+				selected[pod.Name] = true
+			}
+		}
+	}
+
+	kept := make([]corev1.Pod, 0, len(pods))
+	var exclusions []PodExclusion
+	for _, pod := range pods {
+		if selected[pod.Name] {
+			kept = append(kept, pod)
+			continue
+		}
+		exclusions = append(exclusions, PodExclusion{Pod: pod.Name, Reason: fmt.Sprintf("not revision %s", selector.Value)})
+	}
+	return kept, exclusions
+}
+
+// templateImages returns the images of the containers of a pod template
+func templateImages(spec *corev1.PodSpec) []string {
+	images := make([]string, 0, len(spec.Containers))
+	for _, container := range spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// podNodes returns the distinct nodes of scheduled pods, sorted by name
+func podNodes(pods []corev1.Pod) []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" && !seen[pod.Spec.NodeName] {
+			seen[pod.Spec.NodeName] = true
+			nodes = append(nodes, pod.Spec.NodeName)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
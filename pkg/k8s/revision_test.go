@@ -0,0 +1,142 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newRevisionReplicaSet returns a ReplicaSet of revision with the given image
+func newRevisionReplicaSet(name string, revision, image string) appsv1.ReplicaSet {
+	rs := appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			UID:         types.UID(name + "-uid"),
+			Labels:      map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: name[len("web-"):]},
+			Annotations: map[string]string{RevisionAnnotation: revision},
+		},
+	}
+	rs.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web", Image: image}}
+	return rs
+}
+
+func revisionTestData() ([]appsv1.ReplicaSet, []corev1.Pod) {
+	replicaSets := []appsv1.ReplicaSet{
+		newRevisionReplicaSet("web-aaa", "1", "nginx:1.23"),
+		newRevisionReplicaSet("web-bbb", "2", "nginx:1.24"),
+		newRevisionReplicaSet("web-ccc", "3", "nginx:1.25"),
+	}
+	pods := []corev1.Pod{
+		*newTestPod("web-bbb-1", nil, "node1", &replicaSets[1], "ReplicaSet"),
+		*newTestPod("web-ccc-1", nil, "node2", &replicaSets[2], "ReplicaSet"),
+		*newTestPod("web-bbb-2", nil, "node3", &replicaSets[1], "ReplicaSet"),
+		*newTestPod("web-ccc-2", nil, "node1", &replicaSets[2], "ReplicaSet"),
+	}
+	return replicaSets, pods
+}
+
+func TestGroupByRevision(t *testing.T) {
+	replicaSets, pods := revisionTestData()
+
+	groups := GroupByRevision(replicaSets, pods)
+
+	// Revision 1 has no pods left and is omitted
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v", groups)
+	}
+	expected := []struct {
+		replicaSet string
+		revision   int64
+		current    bool
+		image      string
+		nodes      []string
+		pods       int
+	}{
+		{replicaSet: "web-ccc", revision: 3, current: true, image: "nginx:1.25", nodes: []string{"node1", "node2"}, pods: 2},
+		{replicaSet: "web-bbb", revision: 2, image: "nginx:1.24", nodes: []string{"node1", "node3"}, pods: 2},
+	}
+	for i, want := range expected {
+		got := groups[i]
+		if got.ReplicaSet != want.replicaSet || got.Revision != want.revision || got.Current != want.current || len(got.Pods) != want.pods {
+			t.Errorf("Unexpected group %d: %+v", i, got)
+		}
+		if len(got.Images) != 1 || got.Images[0] != want.image {
+			t.Errorf("Expected images [%s], got %v", want.image, got.Images)
+		}
+		if !reflect.DeepEqual(got.Nodes, want.nodes) {
+			t.Errorf("Expected nodes %v, got %v", want.nodes, got.Nodes)
+		}
+	}
+
+	// The newest revision is shown before any of its pods exist
+	groups = GroupByRevision(replicaSets, pods[:1])
+	if len(groups) != 2 || !groups[0].Current || len(groups[0].Pods) != 0 {
+		t.Errorf("Expected an empty newest revision first, got %+v", groups)
+	}
+}
+
+func TestParseRevisionSelector(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *RevisionSelector
+		wantErr bool
+	}{
+		{value: "new", want: &RevisionSelector{Value: RevisionNew}},
+		{value: "OLD", want: &RevisionSelector{Value: RevisionOld}},
+		{value: "3", want: &RevisionSelector{Value: "3", Number: 3}},
+		{value: "0", wantErr: true},
+		{value: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRevisionSelector(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRevisionSelector() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectRevisionPods(t *testing.T) {
+	replicaSets, pods := revisionTestData()
+
+	tests := []struct {
+		selector RevisionSelector
+		wantPods []string
+	}{
+		{selector: RevisionSelector{Value: RevisionNew}, wantPods: []string{"web-ccc-1", "web-ccc-2"}},
+		{selector: RevisionSelector{Value: RevisionOld}, wantPods: []string{"web-bbb-1", "web-bbb-2"}},
+		{selector: RevisionSelector{Value: "2", Number: 2}, wantPods: []string{"web-bbb-1", "web-bbb-2"}},
+		{selector: RevisionSelector{Value: "1", Number: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector.Value, func(t *testing.T) {
+			kept, exclusions := SelectRevisionPods(replicaSets, pods, tt.selector)
+			var names []string
+			for _, pod := range kept {
+				names = append(names, pod.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantPods) {
+				t.Errorf("Expected pods %v, got %v", tt.wantPods, names)
+			}
+			if len(exclusions) != len(pods)-len(kept) {
+				t.Errorf("Expected %d exclusions, got %+v", len(pods)-len(kept), exclusions)
+			}
+			for _, exclusion := range exclusions {
+				if exclusion.Reason != "not revision "+tt.selector.Value {
+					t.Errorf("Unexpected reason %q", exclusion.Reason)
+				}
+			}
+		})
+	}
+}
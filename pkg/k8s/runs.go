@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RunManagerInterface defines operations on the jobs of earlier run-job invocations
+type RunManagerInterface interface {
+	ListRuns(ctx context.Context, namespace string) ([]RunSummary, error)
+	RunStatus(ctx context.Context, namespace, runID string) ([]JobResult, error)
+	DeleteRun(ctx context.Context, namespace, runID string, propagation metav1.DeletionPropagation) ([]string, error)
+}
+
+// RunSummary describes the jobs of one run
+type RunSummary struct {
+	RunID   string
+	Task    string
+	Target  string
+	Version string
+	// Created is when the first job of the run was created
+	Created   time.Time
+	Jobs      int
+	Active    int
+	Succeeded int
+	Failed    int
+}
+
+// RunManager finds runs by the labels set on their jobs
+type RunManager struct {
+	clientset kubernetes.Interface
+}
+
+// NewRunManager creates a new run manager
+func NewRunManager(clientset kubernetes.Interface) RunManagerInterface {
+	return &RunManager{
+		clientset: clientset,
+	}
+}
+
+// ListRuns returns every run with jobs in namespace, newest first
+func (rm *RunManager) ListRuns(ctx context.Context, namespace string) ([]RunSummary, error) {
+	requirement, err := labels.NewRequirement(RunIDLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := rm.listJobs(ctx, namespace, labels.NewSelector().Add(*requirement))
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make(map[string]*RunSummary)
+	for i := range jobs {
+		job := &jobs[i]
+		runID := job.Labels[RunIDLabel]
+		run, ok := runs[runID]
+		if !ok {
+			run = &RunSummary{
+				RunID:   runID,
+				Task:    job.Labels[TaskLabel],
+				Target:  job.Labels[TargetLabel],
+				Version: job.Labels[VersionLabel],
+				Created: job.CreationTimestamp.Time,
+			}
+			runs[runID] = run
+		}
+		if job.CreationTimestamp.Time.Before(run.Created) {
+			run.Created = job.CreationTimestamp.Time
+		}
+
+		run.Jobs++
+		phase, finished := jobPhase(job)
+		switch {
+		case !finished:
+			run.Active++
+		case phase == JobPhaseSucceeded:
+			run.Succeeded++
+		default:
+			run.Failed++
+		}
+	}
+
+	summaries := make([]RunSummary, 0, len(runs))
+	for _, run := range runs {
+		summaries = append(summaries, *run)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if !summaries[i].Created.Equal(summaries[j].Created) {
+			return summaries[i].Created.After(summaries[j].Created)
+		}
+		return summaries[i].RunID < summaries[j].RunID
+	})
+	return summaries, nil
+}
+
+// RunStatus returns the current state of every job of a run, sorted by target.
+// Unfinished jobs are reported as Running, Pending or Unschedulable.
+func (rm *RunManager) RunStatus(ctx context.Context, namespace, runID string) ([]JobResult, error) {
+	jobs, err := rm.runJobs(ctx, namespace, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]JobResult, 0, len(jobs))
+	for i := range jobs {
+		job := &jobs[i]
+		phase, finished := jobPhase(job)
+		message, isUnschedulable := "", false
+		if !finished {
+			phase = JobPhasePending
+			if job.Status.Active > 0 {
+				phase = JobPhaseRunning
+			}
+			if message, isUnschedulable = unschedulable(ctx, rm.clientset, job, 0); isUnschedulable {
+				phase = JobPhaseUnschedulable
+			}
+		}
+		result := newJobResult(ctx, rm.clientset, job, phase)
+		result.Message = message
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Target() < results[j].Target()
+	})
+	return results, nil
+}
+
+// DeleteRun deletes every job of a run together with its pods and returns
+// the names of the deleted jobs. Deletion continues past individual failures.
+func (rm *RunManager) DeleteRun(ctx context.Context, namespace, runID string, propagation metav1.DeletionPropagation) ([]string, error) {
+	jobs, err := rm.runJobs(ctx, namespace, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	var errs []error
+	for _, job := range jobs {
+		err := rm.clientset.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete job %s: %v", job.Name, err))
+			continue
+		}
+		deleted = append(deleted, job.Name)
+	}
+	return deleted, utilerrors.NewAggregate(errs)
+}
+
+// runJobs returns the jobs of a run
+func (rm *RunManager) runJobs(ctx context.Context, namespace, runID string) ([]batchv1.Job, error) {
+	if err := ValidateRunID(runID); err != nil {
+		return nil, err
+	}
+	return rm.listJobs(ctx, namespace, labels.SelectorFromSet(labels.Set{RunIDLabel: runID}))
+}
+
+// listJobs returns the jobs in namespace matching selector
+func (rm *RunManager) listJobs(ctx context.Context, namespace string, selector labels.Selector) ([]batchv1.Job, error) {
+	jobs, err := rm.clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %v", err)
+	}
+	return jobs.Items, nil
+}
@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// createRunJobs creates the jobs of a run with the given state per node
+// ("succeeded", "failed", "running" or "pending")
+func createRunJobs(t *testing.T, clientset *fake.Clientset, runID string, created time.Time, states map[string]string) {
+	t.Helper()
+
+	jm := &JobManager{clientset: clientset}
+	var targets []JobTarget
+	for node := range states {
+		targets = append(targets, JobTarget{Node: node})
+	}
+	jobs, err := jm.BuildJobs("inspect", targets, JobOptions{
+		Namespace: "jobs",
+		Image:     "busybox",
+		Workload:  WorkloadRef{Kind: KindDeployment, Name: "web"},
+		RunID:     runID,
+		Version:   "v1.2.3",
+	})
+	if err != nil {
+		t.Fatalf("BuildJobs() error = %v", err)
+	}
+
+	for _, job := range jobs {
+		job.CreationTimestamp = metav1.NewTime(created)
+		switch states[job.Annotations[NodeAnnotation]] {
+		case "succeeded":
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		case "failed":
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+		case "running":
+			job.Status.Active = 1
+		}
+		if _, err := clientset.BatchV1().Jobs("jobs").Create(context.TODO(), job, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Failed to create job: %v", err)
+		}
+	}
+}
+
+func TestRunManager_ListRuns(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	now := time.Now()
+	createRunJobs(t, clientset, "old", now.Add(-time.Hour), map[string]string{"node1": "succeeded", "node2": "failed"})
+	createRunJobs(t, clientset, "new", now, map[string]string{"node1": "running", "node2": "pending", "node3": "succeeded"})
+
+	// Jobs not created by run-job are ignored
+	if _, err := clientset.BatchV1().Jobs("jobs").Create(context.TODO(), &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "jobs"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+
+	runs, err := NewRunManager(clientset).ListRuns(context.TODO(), "jobs")
+	if err != nil {
+		t.Fatalf("ListRuns() error = %v", err)
+	}
+
+	expected := []RunSummary{
+		{RunID: "new", Task: "inspect", Target: "deployment.web", Version: "v1.2.3", Jobs: 3, Active: 2, Succeeded: 1},
+		{RunID: "old", Task: "inspect", Target: "deployment.web", Version: "v1.2.3", Jobs: 2, Succeeded: 1, Failed: 1},
+	}
+	if len(runs) != len(expected) {
+		t.Fatalf("Expected %d runs, got %d", len(expected), len(runs))
+	}
+	for i := range expected {
+		runs[i].Created = time.Time{}
+		if runs[i] != expected[i] {
+			t.Errorf("Expected run %+v, got %+v", expected[i], runs[i])
+		}
+	}
+}
+
+func TestRunManager_RunStatus(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	createRunJobs(t, clientset, "abc", time.Now(), map[string]string{"node2": "running", "node1": "succeeded", "node3": "pending"})
+	createRunJobs(t, clientset, "other", time.Now(), map[string]string{"node4": "failed"})
+
+	results, err := NewRunManager(clientset).RunStatus(context.TODO(), "jobs", "abc")
+	if err != nil {
+		t.Fatalf("RunStatus() error = %v", err)
+	}
+
+	expected := []struct {
+		node  string
+		phase JobPhase
+	}{
+		{node: "node1", phase: JobPhaseSucceeded},
+		{node: "node2", phase: JobPhaseRunning},
+		{node: "node3", phase: JobPhasePending},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, want := range expected {
+		if results[i].Node != want.node || results[i].Phase != want.phase {
+			t.Errorf("Expected %s %s, got %s %s", want.node, want.phase, results[i].Node, results[i].Phase)
+		}
+	}
+
+	if _, err := NewRunManager(clientset).RunStatus(context.TODO(), "jobs", "Invalid_ID"); err == nil {
+		t.Error("Expected an error for an invalid run ID")
+	}
+}
+
+func TestRunManager_DeleteRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		failNode    string
+		wantDeleted int
+		wantErr     bool
+	}{
+		{name: "delete all jobs", wantDeleted: 2},
+		{name: "partial failure", failNode: "node2", wantDeleted: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			createRunJobs(t, clientset, "abc", time.Now(), map[string]string{"node1": "succeeded", "node2": "running"})
+			createRunJobs(t, clientset, "other", time.Now(), map[string]string{"node1": "succeeded"})
+
+			var propagation metav1.DeletionPropagation
+			clientset.PrependReactor("delete", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				del := action.(k8stesting.DeleteAction)
+				propagation = *del.GetDeleteOptions().PropagationPolicy
+				if del.GetName() == JobInstanceName("inspect", "abc", tt.failNode) {
+					return true, nil, fmt.Errorf("jobs is forbidden")
+				}
+				return false, nil, nil
+			})
+
+			deleted, err := NewRunManager(clientset).DeleteRun(context.TODO(), "jobs", "abc", metav1.DeletePropagationForeground)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeleteRun() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(deleted) != tt.wantDeleted {
+				t.Errorf("Expected %d deleted jobs, got %v", tt.wantDeleted, deleted)
+			}
+			if propagation != metav1.DeletePropagationForeground {
+				t.Errorf("Expected foreground propagation, got %q", propagation)
+			}
+
+			// Jobs of other runs are kept
+			jobs, _ := clientset.BatchV1().Jobs("jobs").List(context.TODO(), metav1.ListOptions{})
+			if len(jobs.Items) != 3-tt.wantDeleted {
+				t.Errorf("Expected %d remaining jobs, got %d", 3-tt.wantDeleted, len(jobs.Items))
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TaintFilter selects the taint keys that are tolerated automatically. Keys
+// ending in * match by prefix. An empty Allow list allows every key; Deny
+// takes precedence over Allow.
+type TaintFilter struct {
+	Allow []string
+	Deny  []string
+}
+
+// allows reports whether a taint key may be tolerated, and why not otherwise
+func (f TaintFilter) allows(key string) (bool, string) {
+	for _, pattern := range f.Deny {
+		if matchTaintKey(pattern, key) {
+			return false, "key is denied"
+		}
+	}
+	if len(f.Allow) == 0 {
+		return true, ""
+	}
+	for _, pattern := range f.Allow {
+		if matchTaintKey(pattern, key) {
+			return true, ""
+		}
+	}
+	return false, "key is not allowed"
+}
+
+// matchTaintKey matches a taint key against an exact key or a prefix ending in *
+func matchTaintKey(pattern, key string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(key, prefix)
+	}
+	return pattern == key
+}
+
+// TaintDecision records whether a taint of a target node is tolerated and why
+type TaintDecision struct {
+	Node  string
+	Taint corev1.Taint
+	// Toleration is the toleration added for the taint; nil if none was added
+	Toleration *corev1.Toleration
+	// Reason explains why no toleration was added
+	Reason string
+}
+
+// TolerateNodeTaints adds a toleration to every target for each taint of its
+// node that passes filter and is not already tolerated by existing. It returns
+// one decision per node taint, in the order of the target nodes.
+func TolerateNodeTaints(targets []JobTarget, nodes map[string]*corev1.Node, existing []corev1.Toleration, filter TaintFilter) []TaintDecision {
+	var decisions []TaintDecision
+	tolerations := make(map[string][]corev1.Toleration)
+
+	for _, name := range TargetNodes(targets) {
+		node, ok := nodes[name]
+		if !ok {
+			continue
+		}
+		for _, taint := range node.Spec.Taints {
+			decision := TaintDecision{Node: name, Taint: taint}
+			if allowed, reason := filter.allows(taint.Key); !allowed {
+				decision.Reason = reason
+			} else if tolerated(existing, taint) {
+				decision.Reason = "already tolerated"
+			} else {
+				toleration := tolerationFor(taint)
+				decision.Toleration = &toleration
+				tolerations[name] = append(tolerations[name], toleration)
+			}
+			decisions = append(decisions, decision)
+		}
+	}
+
+	for i := range targets {
+		targets[i].Tolerations = tolerations[targets[i].Node]
+	}
+	return decisions
+}
+
+// tolerated reports whether any of tolerations tolerates taint
+func tolerated(tolerations []corev1.Toleration, taint corev1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(&taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// tolerationFor returns the toleration matching exactly the given taint
+func tolerationFor(taint corev1.Taint) corev1.Toleration {
+	if taint.Value == "" {
+		return corev1.Toleration{Key: taint.Key, Operator: corev1.TolerationOpExists, Effect: taint.Effect}
+	}
+	return corev1.Toleration{Key: taint.Key, Operator: corev1.TolerationOpEqual, Value: taint.Value, Effect: taint.Effect}
+}
@@ -0,0 +1,111 @@
+package k8s
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTaintFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name       string
+		filter     TaintFilter
+		key        string
+		wantAllow  bool
+		wantReason string
+	}{
+		{name: "empty filter", key: "dedicated", wantAllow: true},
+		{name: "allowed key", filter: TaintFilter{Allow: []string{"dedicated"}}, key: "dedicated", wantAllow: true},
+		{name: "allowed prefix", filter: TaintFilter{Allow: []string{"example.com/*"}}, key: "example.com/gpu", wantAllow: true},
+		{name: "key not allowed", filter: TaintFilter{Allow: []string{"dedicated"}}, key: "gpu", wantReason: "key is not allowed"},
+		{name: "denied prefix", filter: TaintFilter{Deny: []string{"node.kubernetes.io/*"}}, key: "node.kubernetes.io/unschedulable", wantReason: "key is denied"},
+		{name: "deny wins over allow", filter: TaintFilter{Allow: []string{"*"}, Deny: []string{"gpu"}}, key: "gpu", wantReason: "key is denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, reason := tt.filter.allows(tt.key)
+			if allowed != tt.wantAllow || reason != tt.wantReason {
+				t.Errorf("allows(%q) = %v, %q, want %v, %q", tt.key, allowed, reason, tt.wantAllow, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestTolerateNodeTaints(t *testing.T) {
+	gpu := corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	dedicated := corev1.Taint{Key: "dedicated", Effect: corev1.TaintEffectNoExecute}
+	unschedulable := corev1.Taint{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}
+	nodes := map[string]*corev1.Node{
+		"node1": {ObjectMeta: metav1.ObjectMeta{Name: "node1"}, Spec: corev1.NodeSpec{Taints: []corev1.Taint{gpu, unschedulable}}},
+		"node2": {ObjectMeta: metav1.ObjectMeta{Name: "node2"}, Spec: corev1.NodeSpec{Taints: []corev1.Taint{dedicated}}},
+		"node3": {ObjectMeta: metav1.ObjectMeta{Name: "node3"}},
+	}
+	targets := []JobTarget{{Node: "node1"}, {Node: "node2"}, {Node: "node1", Pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-2"}}}, {Node: "node3"}}
+	existing := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+
+	decisions := TolerateNodeTaints(targets, nodes, existing, TaintFilter{Deny: []string{"node.kubernetes.io/*"}})
+
+	expected := []struct {
+		node   string
+		key    string
+		added  bool
+		reason string
+	}{
+		{node: "node1", key: "gpu", added: true},
+		{node: "node1", key: corev1.TaintNodeUnschedulable, reason: "key is denied"},
+		{node: "node2", key: "dedicated", reason: "already tolerated"},
+	}
+	if len(decisions) != len(expected) {
+		t.Fatalf("Expected %d decisions, got %+v", len(expected), decisions)
+	}
+	for i, want := range expected {
+		got := decisions[i]
+		if got.Node != want.node || got.Taint.Key != want.key || (got.Toleration != nil) != want.added || got.Reason != want.reason {
+			t.Errorf("Unexpected decision %d: %+v", i, got)
+		}
+	}
+
+	want := corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	for _, i := range []int{0, 2} {
+		if len(targets[i].Tolerations) != 1 || targets[i].Tolerations[0] != want {
+			t.Errorf("Expected tolerations [%+v] for target %d, got %+v", want, i, targets[i].Tolerations)
+		}
+	}
+	for _, i := range []int{1, 3} {
+		if len(targets[i].Tolerations) != 0 {
+			t.Errorf("Expected no tolerations for target %d, got %+v", i, targets[i].Tolerations)
+		}
+	}
+}
+
+func TestTolerationFor(t *testing.T) {
+	toleration := tolerationFor(corev1.Taint{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule})
+	if toleration.Operator != corev1.TolerationOpExists || toleration.Value != "" {
+		t.Errorf("Expected an Exists toleration for a taint without value, got %+v", toleration)
+	}
+	taint := corev1.Taint{Key: "gpu", Value: "a100", Effect: corev1.TaintEffectNoExecute}
+	toleration = tolerationFor(taint)
+	if toleration.Operator != corev1.TolerationOpEqual || !toleration.ToleratesTaint(&taint) {
+		t.Errorf("Expected an Equal toleration of %+v, got %+v", taint, toleration)
+	}
+}
+
+func TestBuildJob_TargetTolerations(t *testing.T) {
+	shared := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+	target := JobTarget{Node: "node1", Tolerations: []corev1.Toleration{{Key: "gpu", Operator: corev1.TolerationOpExists}}}
+
+	job, err := buildJob("task-abc", target, JobOptions{Namespace: "default", Image: "busybox", Tolerations: shared})
+	if err != nil {
+		t.Fatalf("buildJob() error = %v", err)
+	}
+
+	tolerations := job.Spec.Template.Spec.Tolerations
+	if len(tolerations) != 2 || tolerations[0].Key != "dedicated" || tolerations[1].Key != "gpu" {
+		t.Errorf("Unexpected tolerations %+v", tolerations)
+	}
+	if len(shared) != 1 {
+		t.Errorf("Expected the shared tolerations to be unchanged, got %+v", shared)
+	}
+}
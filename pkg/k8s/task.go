@@ -0,0 +1,202 @@
+package k8s
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+)
+
+// TaskSpec describes what a job runs. Container is merged into the generated
+// job container and Template is merged into the generated pod template using
+// strategic merge patch semantics, so containers are merged by name. Job holds
+// job-level settings such as the TTL and backoff limit.
+type TaskSpec struct {
+	Container json.RawMessage `json:"container,omitempty"`
+	Template  json.RawMessage `json:"template,omitempty"`
+	Job       *JobSettings    `json:"job,omitempty"`
+}
+
+// TaskData holds the values available to task file templates
+type TaskData struct {
+	// NodeName is the node the job runs on
+	NodeName string
+	// Namespace is the namespace of the target workload
+	Namespace string
+	// Deployment is the name of the target workload
+	Deployment string
+	// Workload is the target workload in kind/name form
+	Workload string
+	// Pods are the names of the target pods on the node
+	Pods []string
+}
+
+// NewTaskData builds the template data for a job target
+func NewTaskData(target JobTarget, opts JobOptions) TaskData {
+	data := TaskData{
+		NodeName:   target.Node,
+		Deployment: opts.Workload.Name,
+		Workload:   opts.Workload.String(),
+		Pods:       make([]string, 0, len(target.Pods)),
+	}
+	for _, pod := range target.Pods {
+		data.Namespace = pod.Namespace
+		data.Pods = append(data.Pods, pod.Name)
+	}
+	return data
+}
+
+// Task is a parsed task file that is rendered once per job
+type Task struct {
+	tmpl *template.Template
+}
+
+// taskFuncs are the helper functions available in task templates
+var taskFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// LoadTaskFile reads and parses a YAML or JSON task file
+func LoadTaskFile(path string) (*Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task file %s: %v", path, err)
+	}
+	return ParseTask(data)
+}
+
+// ParseTask parses a task definition. The document is either a TaskSpec with
+// container, template and/or job fields, a bare container, or a bare pod template.
+// Values may use Go templates such as {{.NodeName}} or {{join .Pods ","}}.
+func ParseTask(data []byte) (*Task, error) {
+	tmpl, err := template.New("task").Funcs(taskFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid task template: %v", err)
+	}
+
+	task := &Task{tmpl: tmpl}
+
+	// Render once with placeholder data so that errors surface before any job is created
+	if _, err := task.Render(TaskData{NodeName: "node", Deployment: "workload", Workload: "deployment/workload", Pods: []string{"pod"}}); err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// Render executes the task template with data and decodes the result
+func (t *Task) Render(data TaskData) (*TaskSpec, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render task template: %v", err)
+	}
+
+	doc, err := yaml.YAMLToJSON(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid task definition: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, fmt.Errorf("task definition must be an object: %v", err)
+	}
+
+	spec := &TaskSpec{}
+	switch {
+	case fields["spec"] != nil || fields["metadata"] != nil:
+		spec.Template = doc
+	case fields["container"] != nil || fields["template"] != nil || fields["job"] != nil:
+		if err := yaml.UnmarshalStrict(doc, spec); err != nil {
+			return nil, fmt.Errorf("invalid task definition: %v", err)
+		}
+	default:
+		spec.Container = doc
+	}
+
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// validate checks that the container and template decode into their API types
+func (s *TaskSpec) validate() error {
+	if s.Container != nil {
+		var container corev1.Container
+		if err := yaml.UnmarshalStrict(s.Container, &container); err != nil {
+			return fmt.Errorf("invalid task container: %v", err)
+		}
+	}
+	if s.Template != nil {
+		var podTemplate corev1.PodTemplateSpec
+		if err := yaml.UnmarshalStrict(s.Template, &podTemplate); err != nil {
+			return fmt.Errorf("invalid task template: %v", err)
+		}
+	}
+	return nil
+}
+
+// MergeInto merges the task into a generated pod template
+func (s *TaskSpec) MergeInto(podTemplate *corev1.PodTemplateSpec) error {
+	var patches []json.RawMessage
+
+	if s.Container != nil {
+		var container map[string]interface{}
+		if err := json.Unmarshal(s.Container, &container); err != nil {
+			return fmt.Errorf("invalid task container: %v", err)
+		}
+		// An unnamed container refers to the generated job container
+		if name, _ := container["name"].(string); name == "" {
+			container["name"] = JobContainerName
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{container},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode task container: %v", err)
+		}
+		patches = append(patches, patch)
+	}
+	if s.Template != nil {
+		patches = append(patches, s.Template)
+	}
+
+	original, err := json.Marshal(podTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to encode pod template: %v", err)
+	}
+	for _, patch := range patches {
+		original, err = strategicpatch.StrategicMergePatch(original, patch, corev1.PodTemplateSpec{})
+		if err != nil {
+			return fmt.Errorf("failed to merge task into pod template: %v", err)
+		}
+	}
+
+	merged := corev1.PodTemplateSpec{}
+	if err := json.Unmarshal(original, &merged); err != nil {
+		return fmt.Errorf("failed to decode merged pod template: %v", err)
+	}
+
+	// Patched containers are prepended; keep the job container first so it stays the default
+	containers := merged.Spec.Containers
+	for i := range containers {
+		if containers[i].Name == JobContainerName && i > 0 {
+			jobContainer := containers[i]
+			copy(containers[1:i+1], containers[:i])
+			containers[0] = jobContainer
+			break
+		}
+	}
+	*podTemplate = merged
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package k8s
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseTask(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{
+			name: "task spec with container and template",
+			input: `
+container:
+  command: ["sh", "-c", "echo a,b"]
+template:
+  metadata:
+    labels:
+      team: sre
+`,
+		},
+		{
+			name:  "bare container",
+			input: `{"image": "alpine", "args": ["--node", "{{.NodeName}}"]}`,
+		},
+		{
+			name: "bare pod template",
+			input: `
+spec:
+  serviceAccountName: inspector
+`,
+		},
+		{
+			name: "job settings",
+			input: `
+job:
+  backoffLimit: 0
+  activeDeadlineSeconds: 600
+`,
+		},
+		{
+			name:        "unknown job setting",
+			input:       "job:\n  retries: 3\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid template syntax",
+			input:       "image: {{.NodeName",
+			expectError: true,
+		},
+		{
+			name:        "unknown template field",
+			input:       "image: {{.Unknown}}",
+			expectError: true,
+		},
+		{
+			name:        "unknown task field",
+			input:       "container: {}\nvolumes: []\n",
+			expectError: true,
+		},
+		{
+			name:        "invalid container field",
+			input:       "image: alpine\nimagePullPolicy: [Always]\n",
+			expectError: true,
+		},
+		{
+			name:        "not an object",
+			input:       "- image: alpine\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTask([]byte(tt.input))
+			if (err != nil) != tt.expectError {
+				t.Errorf("ParseTask() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestLoadTaskFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.yaml")
+	if err := os.WriteFile(path, []byte("image: alpine\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write task file: %v", err)
+	}
+
+	if _, err := LoadTaskFile(path); err != nil {
+		t.Errorf("LoadTaskFile() error = %v", err)
+	}
+	if _, err := LoadTaskFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for missing task file but got none")
+	}
+}
+
+func TestTaskSpec_MergeInto(t *testing.T) {
+	task, err := ParseTask([]byte(`
+container:
+  command: ["sh", "-c", 'echo {{.NodeName}} {{.Deployment}} {{join .Pods ","}}']
+  env:
+  - name: TARGET_NODE
+    value: "{{.NodeName}}"
+  resources:
+    limits:
+      memory: 64Mi
+template:
+  metadata:
+    labels:
+      team: sre
+  spec:
+    volumes:
+    - name: data
+      emptyDir: {}
+    containers:
+    - name: sidecar
+      image: busybox
+`))
+	if err != nil {
+		t.Fatalf("ParseTask() error = %v", err)
+	}
+
+	target := JobTarget{
+		Node: "node1",
+		Pods: []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "prod"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "prod"}},
+		},
+	}
+	opts := JobOptions{
+		Namespace: "default",
+		Image:     "busybox",
+		Command:   []string{"true"},
+		Workload:  WorkloadRef{Kind: KindDeployment, Name: "web"},
+		Task:      task,
+	}
+
+	job, err := buildJob("task-abc", target, opts)
+	if err != nil {
+		t.Fatalf("buildJob() error = %v", err)
+	}
+
+	podSpec := job.Spec.Template.Spec
+	if len(podSpec.Containers) != 2 {
+		t.Fatalf("Expected 2 containers, got %d", len(podSpec.Containers))
+	}
+
+	main := podSpec.Containers[0]
+	if main.Name != JobContainerName {
+		t.Errorf("Expected first container %s, got %s", JobContainerName, main.Name)
+	}
+	// The image from the flags is kept while the command is replaced
+	if main.Image != "busybox" {
+		t.Errorf("Expected image busybox, got %s", main.Image)
+	}
+	wantCommand := []string{"sh", "-c", "echo node1 web web-1,web-2"}
+	if len(main.Command) != len(wantCommand) || main.Command[2] != wantCommand[2] {
+		t.Errorf("Expected command %v, got %v", wantCommand, main.Command)
+	}
+	if len(main.Env) != 1 || main.Env[0].Value != "node1" {
+		t.Errorf("Expected TARGET_NODE=node1, got %v", main.Env)
+	}
+	if main.Resources.Limits.Memory().String() != "64Mi" {
+		t.Errorf("Expected memory limit 64Mi, got %s", main.Resources.Limits.Memory())
+	}
+
+	if podSpec.Containers[1].Name != "sidecar" {
+		t.Errorf("Expected sidecar container, got %s", podSpec.Containers[1].Name)
+	}
+	if len(podSpec.Volumes) != 1 || podSpec.Volumes[0].Name != "data" {
+		t.Errorf("Expected data volume, got %v", podSpec.Volumes)
+	}
+
+	// Generated fields survive the merge
+	if podSpec.NodeSelector["kubernetes.io/hostname"] != "node1" {
+		t.Errorf("Expected node selector node1, got %v", podSpec.NodeSelector)
+	}
+	if podSpec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("Expected restart policy Never, got %s", podSpec.RestartPolicy)
+	}
+	labels := job.Spec.Template.Labels
+	if labels["job-name"] != job.Name || labels[TaskLabel] != "task-abc" || labels["team"] != "sre" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+}
@@ -0,0 +1,249 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultPollInterval is how often job status is checked while waiting
+const defaultPollInterval = 2 * time.Second
+
+// unschedulableGracePeriod is how long a job pod may stay unschedulable before
+// the job is reported as Unschedulable instead of being waited for
+const unschedulableGracePeriod = time.Minute
+
+// JobPhase describes the state of a job as observed by WaitForJobs or RunStatus
+type JobPhase string
+
+const (
+	// JobPhaseSucceeded means the job reached the Complete condition
+	JobPhaseSucceeded JobPhase = "Succeeded"
+	// JobPhaseFailed means the job reached the Failed condition
+	JobPhaseFailed JobPhase = "Failed"
+	// JobPhaseTimeout means the job did not finish before the timeout expired
+	JobPhaseTimeout JobPhase = "Timeout"
+	// JobPhaseUnknown means the job disappeared before its result was observed
+	JobPhaseUnknown JobPhase = "Unknown"
+	// JobPhaseRunning means the job has not finished and has an active pod
+	JobPhaseRunning JobPhase = "Running"
+	// JobPhasePending means the job has not finished and has no active pod yet
+	JobPhasePending JobPhase = "Pending"
+	// JobPhaseUnschedulable means the scheduler could not place the job pod on its node
+	JobPhaseUnschedulable JobPhase = "Unschedulable"
+)
+
+// JobResult holds the observed outcome of a single job
+type JobResult struct {
+	Node string
+	// TargetPod is the target pod of a per-pod job or debug container
+	TargetPod string
+	Job       string
+	Pod       string
+	// Container is the debug container name in ephemeral mode; empty for jobs
+	Container string
+	Phase     JobPhase
+	ExitCode  *int32
+	Duration  time.Duration
+	// Message explains an Unschedulable phase with the scheduler's reason, or an
+	// Unknown phase with the error that ended the wait
+	Message string
+}
+
+// Target identifies what the job ran against, as node or node/pod
+func (r JobResult) Target() string {
+	return TargetName(r.Node, r.TargetPod)
+}
+
+// Succeeded reports whether the job completed successfully
+func (r JobResult) Succeeded() bool {
+	return r.Phase == JobPhaseSucceeded
+}
+
+// WaitForJobs waits until every job completes, fails, or the timeout expires.
+// Jobs whose pod stays unschedulable for unschedulableGracePeriod are reported
+// as Unschedulable and no longer waited for, since they would never finish.
+// A zero timeout waits indefinitely. Results are returned in the order of jobs
+// and keep the target of their creation even if the job can no longer be read.
+// If ctx is canceled the results observed so far are returned with an error.
+func (jm *JobManager) WaitForJobs(ctx context.Context, namespace string, jobs []JobCreation, timeout time.Duration) ([]JobResult, error) {
+	results := make([]JobResult, len(jobs))
+	pending := make(map[string]int, len(jobs))
+	for i, job := range jobs {
+		results[i] = JobResult{Node: job.Node, TargetPod: job.TargetPod, Job: job.Job}
+		pending[job.Job] = i
+	}
+
+	waitCtx, cancel := waitContext(ctx, timeout)
+	defer cancel()
+
+	interval := jm.pollInterval
+	if interval == 0 {
+		interval = defaultPollInterval
+	}
+
+	err := wait.PollUntilContextCancel(waitCtx, interval, true, func(ctx context.Context) (bool, error) {
+		for name, i := range pending {
+			job, err := jm.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				if apierrors.IsNotFound(err) {
+					results[i].Phase = JobPhaseUnknown
+					delete(pending, name)
+				} else if category := CategorizeError(err); category.Terminal() {
+					// Retrying cannot fix missing permissions; stop waiting for the job
+					results[i].Phase = JobPhaseUnknown
+					results[i].Message = fmt.Sprintf("failed to get job [%s]: %v", category, err)
+					delete(pending, name)
+				}
+				// Other errors are treated as transient and retried on the next tick
+				continue
+			}
+
+			phase, finished := jobPhase(job)
+			if !finished {
+				if message, ok := unschedulable(ctx, jm.clientset, job, unschedulableGracePeriod); ok {
+					results[i] = newJobResult(ctx, jm.clientset, job, JobPhaseUnschedulable)
+					results[i].Message = message
+					delete(pending, name)
+				}
+				continue
+			}
+			results[i] = newJobResult(ctx, jm.clientset, job, phase)
+			delete(pending, name)
+		}
+		return len(pending) == 0, nil
+	})
+	if ctx.Err() != nil {
+		return results, fmt.Errorf("stopped waiting for jobs: %v", ctx.Err())
+	}
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return results, fmt.Errorf("failed to wait for jobs: %v", err)
+	}
+
+	for name, i := range pending {
+		job, err := jm.clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			results[i].Phase = JobPhaseTimeout
+			continue
+		}
+		results[i] = newJobResult(ctx, jm.clientset, job, JobPhaseTimeout)
+	}
+
+	return results, nil
+}
+
+// waitContext returns a child of ctx bounded by timeout; zero means no limit
+func waitContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// jobPhase returns the terminal phase of a job and whether it has finished
+func jobPhase(job *batchv1.Job) (JobPhase, bool) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return JobPhaseSucceeded, true
+		case batchv1.JobFailed:
+			return JobPhaseFailed, true
+		}
+	}
+	return "", false
+}
+
+// unschedulable reports whether the pod of an unfinished job has been
+// unschedulable for at least grace, together with the scheduler's message
+func unschedulable(ctx context.Context, clientset kubernetes.Interface, job *batchv1.Job, grace time.Duration) (string, bool) {
+	// Ready pods have been scheduled
+	if job.Status.Active == 0 || (job.Status.Ready != nil && *job.Status.Ready > 0) {
+		return "", false
+	}
+
+	pod, err := latestJobPod(ctx, clientset, job.Namespace, job.Name)
+	if err != nil || pod == nil || pod.Spec.NodeName != "" {
+		return "", false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type != corev1.PodScheduled || cond.Status != corev1.ConditionFalse || cond.Reason != corev1.PodReasonUnschedulable {
+			continue
+		}
+		if time.Since(cond.LastTransitionTime.Time) >= grace {
+			return cond.Message, true
+		}
+	}
+	return "", false
+}
+
+// newJobResult builds a JobResult from a job and the most recent pod it created
+func newJobResult(ctx context.Context, clientset kubernetes.Interface, job *batchv1.Job, phase JobPhase) JobResult {
+	result := JobResult{
+		Node:      job.Annotations[NodeAnnotation],
+		TargetPod: job.Annotations[PodAnnotation],
+		Job:       job.Name,
+		Phase:     phase,
+	}
+
+	if job.Status.StartTime != nil {
+		end := time.Now()
+		if job.Status.CompletionTime != nil {
+			end = job.Status.CompletionTime.Time
+		} else if phase == JobPhaseFailed {
+			for _, cond := range job.Status.Conditions {
+				if cond.Type == batchv1.JobFailed {
+					end = cond.LastTransitionTime.Time
+				}
+			}
+		}
+		result.Duration = end.Sub(job.Status.StartTime.Time)
+	}
+
+	pod, err := latestJobPod(ctx, clientset, job.Namespace, job.Name)
+	if err != nil || pod == nil {
+		return result
+	}
+	result.Pod = pod.Name
+	if result.Node == "" {
+		result.Node = pod.Spec.NodeName
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			exitCode := status.State.Terminated.ExitCode
+			result.ExitCode = &exitCode
+			break
+		}
+	}
+
+	return result
+}
+
+// latestJobPod returns the most recently created pod of a job, or nil if there is none
+func latestJobPod(ctx context.Context, clientset kubernetes.Interface, namespace, jobName string) (*corev1.Pod, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", jobName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods for job %s: %v", jobName, err)
+	}
+
+	var latest *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if latest == nil || latest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			latest = pod
+		}
+	}
+	return latest, nil
+}
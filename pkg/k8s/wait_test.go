@@ -0,0 +1,243 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestJob(name, node string, condition batchv1.JobConditionType) *batchv1.Job {
+	start := metav1.NewTime(time.Now().Add(-time.Minute))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: map[string]string{NodeAnnotation: node},
+		},
+		Status: batchv1.JobStatus{StartTime: &start},
+	}
+	if condition != "" {
+		job.Status.Conditions = []batchv1.JobCondition{
+			{Type: condition, Status: corev1.ConditionTrue, LastTransitionTime: metav1.Now()},
+		}
+	}
+	return job
+}
+
+// newTestCreation returns the creation of a job submitted for node
+func newTestCreation(name, node string) JobCreation {
+	return JobCreation{Node: node, Job: name}
+}
+
+func newTestJobPod(jobName, node string, exitCode int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName + "-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{"job-name": jobName},
+		},
+		Spec: corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "job-container",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode},
+					},
+				},
+			},
+		},
+	}
+}
+
+// newUnschedulableJobPod returns the pending pod of an active job that the
+// scheduler has failed to place since the given time
+func newUnschedulableJobPod(job *batchv1.Job, since time.Time) *corev1.Pod {
+	job.Status.Active = 1
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      job.Name + "-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{"job-name": job.Name},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:               corev1.PodScheduled,
+				Status:             corev1.ConditionFalse,
+				Reason:             corev1.PodReasonUnschedulable,
+				Message:            "0/3 nodes are available: 3 node(s) didn't match Pod's node affinity/selector.",
+				LastTransitionTime: metav1.NewTime(since),
+			}},
+		},
+	}
+}
+
+func TestJobManager_WaitForJobs(t *testing.T) {
+	unschedulableJob := newTestJob("job-a", "node1", "")
+	unschedulablePod := newUnschedulableJobPod(unschedulableJob, time.Now().Add(-2*unschedulableGracePeriod))
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		jobs    []JobCreation
+		timeout time.Duration
+		getErr  error
+		// deleteAfter deletes the jobs after that many reads, as if removed while waiting
+		deleteAfter int
+		wantPhase   []JobPhase
+		wantExit    []*int32
+		wantNode    []string
+		wantMsg     string
+	}{
+		{
+			name: "all jobs succeed",
+			objects: []runtime.Object{
+				newTestJob("job-a", "node1", batchv1.JobComplete),
+				newTestJobPod("job-a", "node1", 0),
+				newTestJob("job-b", "node2", batchv1.JobComplete),
+				newTestJobPod("job-b", "node2", 0),
+			},
+			jobs:      []JobCreation{newTestCreation("job-a", "node1"), newTestCreation("job-b", "node2")},
+			timeout:   time.Second,
+			wantPhase: []JobPhase{JobPhaseSucceeded, JobPhaseSucceeded},
+			wantExit:  []*int32{int32Ptr(0), int32Ptr(0)},
+			wantNode:  []string{"node1", "node2"},
+		},
+		{
+			name: "one job fails",
+			objects: []runtime.Object{
+				newTestJob("job-a", "node1", batchv1.JobComplete),
+				newTestJobPod("job-a", "node1", 0),
+				newTestJob("job-b", "node2", batchv1.JobFailed),
+				newTestJobPod("job-b", "node2", 3),
+			},
+			jobs:      []JobCreation{newTestCreation("job-a", "node1"), newTestCreation("job-b", "node2")},
+			timeout:   time.Second,
+			wantPhase: []JobPhase{JobPhaseSucceeded, JobPhaseFailed},
+			wantExit:  []*int32{int32Ptr(0), int32Ptr(3)},
+			wantNode:  []string{"node1", "node2"},
+		},
+		{
+			name: "job does not finish before timeout",
+			objects: []runtime.Object{
+				newTestJob("job-a", "node1", ""),
+			},
+			jobs:      []JobCreation{newTestCreation("job-a", "node1")},
+			timeout:   50 * time.Millisecond,
+			wantPhase: []JobPhase{JobPhaseTimeout},
+			wantExit:  []*int32{nil},
+			wantNode:  []string{"node1"},
+		},
+		{
+			name:      "job pod cannot be scheduled",
+			objects:   []runtime.Object{unschedulableJob, unschedulablePod},
+			jobs:      []JobCreation{newTestCreation("job-a", "node1")},
+			timeout:   5 * time.Second,
+			wantPhase: []JobPhase{JobPhaseUnschedulable},
+			wantExit:  []*int32{nil},
+			wantNode:  []string{"node1"},
+		},
+		{
+			name:      "job no longer exists",
+			objects:   nil,
+			jobs:      []JobCreation{newTestCreation("job-a", "node1")},
+			timeout:   time.Second,
+			wantPhase: []JobPhase{JobPhaseUnknown},
+			wantExit:  []*int32{nil},
+			wantNode:  []string{"node1"},
+		},
+		{
+			name:        "job deleted while waiting",
+			objects:     []runtime.Object{newTestJob("job-a", "node1", "")},
+			jobs:        []JobCreation{newTestCreation("job-a", "node1")},
+			deleteAfter: 1,
+			wantPhase:   []JobPhase{JobPhaseUnknown},
+			wantExit:    []*int32{nil},
+			wantNode:    []string{"node1"},
+		},
+		{
+			// Without a timeout a retried Forbidden error would wait forever
+			name:      "job cannot be read",
+			objects:   []runtime.Object{newTestJob("job-a", "node1", "")},
+			jobs:      []JobCreation{newTestCreation("job-a", "node1")},
+			getErr:    apierrors.NewForbidden(batchv1.Resource("jobs"), "job-a", fmt.Errorf("RBAC denied")),
+			wantPhase: []JobPhase{JobPhaseUnknown},
+			wantExit:  []*int32{nil},
+			wantNode:  []string{"node1"},
+			wantMsg:   "failed to get job [Forbidden]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(tt.objects...)
+			if tt.getErr != nil {
+				clientset.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.getErr
+				})
+			}
+			if tt.deleteAfter > 0 {
+				reads := 0
+				clientset.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					if reads++; reads > tt.deleteAfter {
+						name := action.(k8stesting.GetAction).GetName()
+						if err := clientset.Tracker().Delete(batchv1.SchemeGroupVersion.WithResource("jobs"), "default", name); err != nil && !apierrors.IsNotFound(err) {
+							t.Errorf("Failed to delete job %s: %v", name, err)
+						}
+					}
+					return false, nil, nil
+				})
+			}
+			jm := &JobManager{clientset: clientset, pollInterval: 10 * time.Millisecond}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			results, err := jm.WaitForJobs(ctx, "default", tt.jobs, tt.timeout)
+			if err != nil {
+				t.Fatalf("WaitForJobs() error = %v", err)
+			}
+
+			if len(results) != len(tt.jobs) {
+				t.Fatalf("Expected %d results, got %d", len(tt.jobs), len(results))
+			}
+
+			for i, result := range results {
+				if result.Job != tt.jobs[i].Job {
+					t.Errorf("Expected job %s at index %d, got %s", tt.jobs[i].Job, i, result.Job)
+				}
+				if result.Phase != tt.wantPhase[i] {
+					t.Errorf("Expected phase %s for job %s, got %s", tt.wantPhase[i], result.Job, result.Phase)
+				}
+				if !strings.HasPrefix(result.Message, tt.wantMsg) {
+					t.Errorf("Expected message starting with %q for job %s, got %q", tt.wantMsg, result.Job, result.Message)
+				}
+				if result.Node != tt.wantNode[i] {
+					t.Errorf("Expected node %s for job %s, got %s", tt.wantNode[i], result.Job, result.Node)
+				}
+				switch {
+				case tt.wantExit[i] == nil && result.ExitCode != nil:
+					t.Errorf("Expected no exit code for job %s, got %d", result.Job, *result.ExitCode)
+				case tt.wantExit[i] != nil && result.ExitCode == nil:
+					t.Errorf("Expected exit code %d for job %s, got none", *tt.wantExit[i], result.Job)
+				case tt.wantExit[i] != nil && *result.ExitCode != *tt.wantExit[i]:
+					t.Errorf("Expected exit code %d for job %s, got %d", *tt.wantExit[i], result.Job, *result.ExitCode)
+				}
+			}
+		})
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
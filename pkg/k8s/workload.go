@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// WorkloadKind identifies the kind of workload whose pods are targeted
+type WorkloadKind string
+
+const (
+	// KindDeployment targets the pods of an apps/v1 Deployment
+	KindDeployment WorkloadKind = "Deployment"
+	// KindStatefulSet targets the pods of an apps/v1 StatefulSet
+	KindStatefulSet WorkloadKind = "StatefulSet"
+	// KindDaemonSet targets the pods of an apps/v1 DaemonSet
+	KindDaemonSet WorkloadKind = "DaemonSet"
+	// KindReplicaSet targets the pods of an apps/v1 ReplicaSet
+	KindReplicaSet WorkloadKind = "ReplicaSet"
+	// KindSelector targets the pods matching a raw label selector
+	KindSelector WorkloadKind = "Selector"
+)
+
+// workloadKindAliases maps the accepted kind prefixes to workload kinds
+var workloadKindAliases = map[string]WorkloadKind{
+	"deployment":   KindDeployment,
+	"deployments":  KindDeployment,
+	"deploy":       KindDeployment,
+	"statefulset":  KindStatefulSet,
+	"statefulsets": KindStatefulSet,
+	"sts":          KindStatefulSet,
+	"daemonset":    KindDaemonSet,
+	"daemonsets":   KindDaemonSet,
+	"ds":           KindDaemonSet,
+	"replicaset":   KindReplicaSet,
+	"replicasets":  KindReplicaSet,
+	"rs":           KindReplicaSet,
+}
+
+// WorkloadRef identifies a set of target pods: either a named workload or a label selector
+type WorkloadRef struct {
+	Kind     WorkloadKind
+	Name     string
+	Selector string
+}
+
+// ParseWorkloadRef parses a kind/name reference such as statefulset/redis or ds/fluentd.
+// A bare name refers to a Deployment.
+func ParseWorkloadRef(ref string) (WorkloadRef, error) {
+	if ref == "" {
+		return WorkloadRef{}, fmt.Errorf("workload reference must not be empty")
+	}
+
+	if !strings.Contains(ref, "/") {
+		return WorkloadRef{Kind: KindDeployment, Name: ref}, nil
+	}
+
+	parts := strings.SplitN(ref, "/", 2)
+	kind, ok := workloadKindAliases[strings.ToLower(parts[0])]
+	if !ok {
+		return WorkloadRef{}, fmt.Errorf("unsupported workload kind: %s (expected deployment, statefulset, daemonset or replicaset)", parts[0])
+	}
+	if parts[1] == "" || strings.Contains(parts[1], "/") {
+		return WorkloadRef{}, fmt.Errorf("invalid workload reference: %s (expected kind/name)", ref)
+	}
+
+	return WorkloadRef{Kind: kind, Name: parts[1]}, nil
+}
+
+// NewSelectorWorkloadRef creates a reference to the pods matching a label selector
+func NewSelectorWorkloadRef(selector string) (WorkloadRef, error) {
+	if _, err := labels.Parse(selector); err != nil {
+		return WorkloadRef{}, fmt.Errorf("invalid label selector %q: %v", selector, err)
+	}
+	return WorkloadRef{Kind: KindSelector, Selector: selector}, nil
+}
+
+// String returns the reference in kind/name form
+func (r WorkloadRef) String() string {
+	if r.Kind == KindSelector {
+		return fmt.Sprintf("selector %q", r.Selector)
+	}
+	return fmt.Sprintf("%s/%s", strings.ToLower(string(r.Kind)), r.Name)
+}
+
+// WorkloadResolverInterface defines operations for resolving workloads to pods
+type WorkloadResolverInterface interface {
+	GetPods(ctx context.Context, ref WorkloadRef, namespace string) ([]corev1.Pod, error)
+	CurrentRevision(ctx context.Context, ref WorkloadRef, namespace string) (*PodRevision, error)
+}
+
+// WorkloadResolver resolves Deployments, StatefulSets, DaemonSets, ReplicaSets
+// and label selectors to the pods they select
+type WorkloadResolver struct {
+	clientset kubernetes.Interface
+}
+
+// NewWorkloadResolver creates a new workload resolver
+func NewWorkloadResolver(clientset kubernetes.Interface) WorkloadResolverInterface {
+	return &WorkloadResolver{
+		clientset: clientset,
+	}
+}
+
+// GetPods returns the pods selected by the referenced workload. For named
+// workloads only pods controlled by that workload are returned.
+func (wr *WorkloadResolver) GetPods(ctx context.Context, ref WorkloadRef, namespace string) ([]corev1.Pod, error) {
+	var owner metav1.Object
+	var selector *metav1.LabelSelector
+
+	switch ref.Kind {
+	case KindDeployment:
+		return NewDeploymentManager(wr.clientset).GetPodsFromDeployment(ctx, ref.Name, namespace)
+	case KindStatefulSet:
+		sts, err := wr.clientset.AppsV1().StatefulSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s: %v", ref.Name, err)
+		}
+		owner, selector = sts, sts.Spec.Selector
+	case KindDaemonSet:
+		ds, err := wr.clientset.AppsV1().DaemonSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daemonset %s: %v", ref.Name, err)
+		}
+		owner, selector = ds, ds.Spec.Selector
+	case KindReplicaSet:
+		rs, err := wr.clientset.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get replicaset %s: %v", ref.Name, err)
+		}
+		owner, selector = rs, rs.Spec.Selector
+	case KindSelector:
+		return wr.listPods(ctx, namespace, ref.Selector)
+	default:
+		return nil, fmt.Errorf("unsupported workload kind: %s", ref.Kind)
+	}
+
+	if selector == nil {
+		return nil, fmt.Errorf("%s has no selector", ref)
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on %s: %v", ref, err)
+	}
+
+	pods, err := wr.listPods(ctx, namespace, labelSelector.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return filterControlledPods(pods, map[types.UID]bool{owner.GetUID(): true}), nil
+}
+
+// listPods lists the pods in namespace matching a label selector string
+func (wr *WorkloadResolver) listPods(ctx context.Context, namespace, selector string) ([]corev1.Pod, error) {
+	pods, err := wr.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	return pods.Items, nil
+}
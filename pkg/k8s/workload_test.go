@@ -0,0 +1,198 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseWorkloadRef(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    WorkloadRef
+		expectError bool
+	}{
+		{
+			name:     "bare name is a deployment",
+			input:    "nginx",
+			expected: WorkloadRef{Kind: KindDeployment, Name: "nginx"},
+		},
+		{
+			name:     "deployment kind",
+			input:    "deployment/nginx",
+			expected: WorkloadRef{Kind: KindDeployment, Name: "nginx"},
+		},
+		{
+			name:     "statefulset kind",
+			input:    "statefulset/redis",
+			expected: WorkloadRef{Kind: KindStatefulSet, Name: "redis"},
+		},
+		{
+			name:     "daemonset short name",
+			input:    "ds/fluentd",
+			expected: WorkloadRef{Kind: KindDaemonSet, Name: "fluentd"},
+		},
+		{
+			name:     "replicaset short name",
+			input:    "rs/foo",
+			expected: WorkloadRef{Kind: KindReplicaSet, Name: "foo"},
+		},
+		{
+			name:     "case insensitive kind",
+			input:    "StatefulSet/redis",
+			expected: WorkloadRef{Kind: KindStatefulSet, Name: "redis"},
+		},
+		{
+			name:        "unknown kind",
+			input:       "cronjob/backup",
+			expectError: true,
+		},
+		{
+			name:        "missing name",
+			input:       "sts/",
+			expectError: true,
+		},
+		{
+			name:        "too many segments",
+			input:       "sts/redis/0",
+			expectError: true,
+		},
+		{
+			name:        "empty string",
+			input:       "",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseWorkloadRef(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ref != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, ref)
+			}
+		})
+	}
+}
+
+func newTestPod(name string, labels map[string]string, node string, owner metav1.Object, ownerKind string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+	if owner != nil {
+		gvk := appsv1.SchemeGroupVersion.WithKind(ownerKind)
+		pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, gvk)}
+	}
+	return pod
+}
+
+func TestWorkloadResolver_GetPods(t *testing.T) {
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Namespace: "default", UID: "sts-uid"},
+		Spec: appsv1.StatefulSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "redis"}},
+		},
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "fluentd", Namespace: "default", UID: "ds-uid"},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"fluentd"}},
+				},
+			},
+		},
+	}
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", UID: "rs-uid"},
+		Spec: appsv1.ReplicaSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+	objects := []runtime.Object{
+		sts, ds, rs,
+		newTestPod("redis-0", map[string]string{"app": "redis"}, "node1", sts, "StatefulSet"),
+		newTestPod("redis-1", map[string]string{"app": "redis"}, "node2", sts, "StatefulSet"),
+		newTestPod("redis-debug", map[string]string{"app": "redis"}, "node3", nil, ""),
+		newTestPod("fluentd-x", map[string]string{"app": "fluentd", "tier": "logging"}, "node1", ds, "DaemonSet"),
+		newTestPod("web-abc-1", map[string]string{"app": "web"}, "node3", rs, "ReplicaSet"),
+	}
+
+	tests := []struct {
+		name        string
+		ref         WorkloadRef
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "statefulset excludes pods it does not own",
+			ref:      WorkloadRef{Kind: KindStatefulSet, Name: "redis"},
+			expected: []string{"redis-0", "redis-1"},
+		},
+		{
+			name:     "daemonset with match expressions",
+			ref:      WorkloadRef{Kind: KindDaemonSet, Name: "fluentd"},
+			expected: []string{"fluentd-x"},
+		},
+		{
+			name:     "replicaset",
+			ref:      WorkloadRef{Kind: KindReplicaSet, Name: "web-abc"},
+			expected: []string{"web-abc-1"},
+		},
+		{
+			name:     "label selector",
+			ref:      WorkloadRef{Kind: KindSelector, Selector: "tier=logging"},
+			expected: []string{"fluentd-x"},
+		},
+		{
+			name:        "missing statefulset",
+			ref:         WorkloadRef{Kind: KindStatefulSet, Name: "missing"},
+			expectError: true,
+		},
+	}
+
+	resolver := NewWorkloadResolver(fake.NewSimpleClientset(objects...))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pods, err := resolver.GetPods(context.TODO(), tt.ref, "default")
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(pods) != len(tt.expected) {
+				t.Fatalf("Expected %d pods, got %d", len(tt.expected), len(pods))
+			}
+			podMap := make(map[string]bool)
+			for _, pod := range pods {
+				podMap[pod.Name] = true
+			}
+			for _, name := range tt.expected {
+				if !podMap[name] {
+					t.Errorf("Expected pod %s not found in result", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FormatTable is the default human-readable table
+	FormatTable = ""
+	// FormatWide is the human-readable table with additional columns
+	FormatWide = "wide"
+	// FormatJSON prints the result as JSON
+	FormatJSON = "json"
+	// FormatYAML prints the result as YAML
+	FormatYAML = "yaml"
+	// FormatName prints only resource names
+	FormatName = "name"
+	// FormatGoTemplate renders the result with a Go template
+	FormatGoTemplate = "go-template"
+	// FormatJSONPath renders the result with a JSONPath expression
+	FormatJSONPath = "jsonpath"
+)
+
+// Format is a parsed --output value
+type Format struct {
+	Name     string
+	Template string
+}
+
+// Named is implemented by results that can be printed with -o name
+type Named interface {
+	Names() []string
+}
+
+// ParseFormat parses an --output value such as json, wide or jsonpath={.pods[*].name}
+func ParseFormat(spec string) (Format, error) {
+	name, tmpl, hasTemplate := strings.Cut(spec, "=")
+
+	switch name {
+	case FormatTable, FormatWide, FormatJSON, FormatYAML, FormatName:
+		if hasTemplate {
+			return Format{}, fmt.Errorf("output format %s does not take a template", name)
+		}
+		return Format{Name: name}, nil
+	case FormatGoTemplate, FormatJSONPath:
+		if tmpl == "" {
+			return Format{}, fmt.Errorf("output format %s requires a template, e.g. %s=...", name, name)
+		}
+		return Format{Name: name, Template: tmpl}, nil
+	default:
+		return Format{}, fmt.Errorf("unsupported output format: %s (expected json, yaml, wide, name, go-template=... or jsonpath=...)", spec)
+	}
+}
+
+// IsTable reports whether the format is one of the human-readable tables
+func (f Format) IsTable() bool {
+	return f.Name == FormatTable || f.Name == FormatWide
+}
+
+// Print writes obj to w in a machine-readable format. Table formats are
+// rendered by the caller and are rejected here.
+func Print(w io.Writer, f Format, obj interface{}) error {
+	switch f.Name {
+	case FormatJSON:
+		data, err := json.MarshalIndent(obj, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %v", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case FormatYAML:
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to encode YAML: %v", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatName:
+		named, ok := obj.(Named)
+		if !ok {
+			return fmt.Errorf("output format name is not supported for %T", obj)
+		}
+		for _, name := range named.Names() {
+			if _, err := fmt.Fprintln(w, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatGoTemplate:
+		data, err := toGeneric(obj)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New("output").Parse(f.Template)
+		if err != nil {
+			return fmt.Errorf("invalid go-template: %v", err)
+		}
+		return tmpl.Execute(w, data)
+	case FormatJSONPath:
+		data, err := toGeneric(obj)
+		if err != nil {
+			return err
+		}
+		jp := jsonpath.New("output")
+		if err := jp.Parse(f.Template); err != nil {
+			return fmt.Errorf("invalid jsonpath: %v", err)
+		}
+		return jp.Execute(w, data)
+	default:
+		return fmt.Errorf("output format %q must be printed as a table", f.Name)
+	}
+}
+
+// toGeneric converts obj into maps and slices keyed by its JSON field names,
+// so templates address fields the same way as the JSON output does
+func toGeneric(obj interface{}) (interface{}, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %v", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to decode result: %v", err)
+	}
+	return generic, nil
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    Format
+		expectError bool
+	}{
+		{name: "default table", input: "", expected: Format{Name: FormatTable}},
+		{name: "wide", input: "wide", expected: Format{Name: FormatWide}},
+		{name: "json", input: "json", expected: Format{Name: FormatJSON}},
+		{name: "yaml", input: "yaml", expected: Format{Name: FormatYAML}},
+		{name: "name", input: "name", expected: Format{Name: FormatName}},
+		{
+			name:     "go-template",
+			input:    "go-template={{.target}}",
+			expected: Format{Name: FormatGoTemplate, Template: "{{.target}}"},
+		},
+		{
+			name:     "jsonpath containing equals sign",
+			input:    "jsonpath={.pods[?(@.phase==\"Running\")].name}",
+			expected: Format{Name: FormatJSONPath, Template: "{.pods[?(@.phase==\"Running\")].name}"},
+		},
+		{name: "jsonpath without template", input: "jsonpath=", expectError: true},
+		{name: "json with template", input: "json={.pods}", expectError: true},
+		{name: "unknown format", input: "xml", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFormat(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if f != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, f)
+			}
+		})
+	}
+}
+
+func TestPrint(t *testing.T) {
+	result := &ListResult{
+		APIVersion: APIVersion,
+		Kind:       KindListResult,
+		Target:     "deployment/web",
+		Namespace:  "default",
+		Pods: []PodInfo{
+			{Name: "web-1", Namespace: "default", Node: "node1", Phase: "Running", PodIP: "10.0.0.1"},
+			{Name: "web-2", Namespace: "default", Node: "node2", Phase: "Pending"},
+		},
+		Nodes: []NodeInfo{
+			{Name: "node1", Pods: []string{"web-1"}},
+			{Name: "node2", Pods: []string{"web-2"}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		format      Format
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "name",
+			format:   Format{Name: FormatName},
+			expected: "pod/web-1\npod/web-2\n",
+		},
+		{
+			name:     "yaml",
+			format:   Format{Name: FormatYAML},
+			expected: "apiVersion: deployment-inspector/v1\nkind: ListResult\nnamespace: default\nnodes:\n- name: node1\n  pods:\n  - web-1\n- name: node2\n  pods:\n  - web-2\npods:\n- name: web-1\n  namespace: default\n  node: node1\n  phase: Running\n  podIP: 10.0.0.1\n- name: web-2\n  namespace: default\n  node: node2\n  phase: Pending\ntarget: deployment/web\n",
+		},
+		{
+			name:     "go-template uses json field names",
+			format:   Format{Name: FormatGoTemplate, Template: "{{range .nodes}}{{.name}} {{end}}"},
+			expected: "node1 node2 ",
+		},
+		{
+			name:     "jsonpath",
+			format:   Format{Name: FormatJSONPath, Template: "{.pods[*].podIP}"},
+			expected: "10.0.0.1",
+		},
+		{
+			name:        "table is rendered by the caller",
+			format:      Format{Name: FormatTable},
+			expectError: true,
+		},
+		{
+			name:        "invalid template",
+			format:      Format{Name: FormatGoTemplate, Template: "{{.target"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Print(&buf, tt.format, result)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,601 @@
+package output
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// APIVersion is the schema version of the machine-readable results.
+// It must be bumped whenever a field is renamed or removed.
+const APIVersion = "deployment-inspector/v1"
+
+const (
+	// KindListResult is the kind of the list command result
+	KindListResult = "ListResult"
+	// KindRunJobResult is the kind of the run-job command result
+	KindRunJobResult = "RunJobResult"
+	// KindMultiClusterRunJobResult is the kind of the run-job result across several clusters
+	KindMultiClusterRunJobResult = "MultiClusterRunJobResult"
+	// KindExecResult is the kind of the exec command result
+	KindExecResult = "ExecResult"
+	// KindRunList is the kind of the runs list command result
+	KindRunList = "RunList"
+	// KindRunStatus is the kind of the runs status command result
+	KindRunStatus = "RunStatus"
+)
+
+// PodInfo describes a target pod
+type PodInfo struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Node      string `json:"node,omitempty"`
+	Phase     string `json:"phase"`
+	PodIP     string `json:"podIP,omitempty"`
+	HostIP    string `json:"hostIP,omitempty"`
+}
+
+// NodeInfo describes a node running target pods
+type NodeInfo struct {
+	Name string   `json:"name"`
+	Pods []string `json:"pods"`
+}
+
+// JobInfo describes a job (or ephemeral debug container) created by run-job and, when waited for, its outcome
+type JobInfo struct {
+	Name      string  `json:"name"`
+	Namespace string  `json:"namespace"`
+	Node      string  `json:"node,omitempty"`
+	TargetPod string  `json:"targetPod,omitempty"`
+	Pod       string  `json:"pod,omitempty"`
+	Container string  `json:"container,omitempty"`
+	Phase     string  `json:"phase,omitempty"`
+	ExitCode  *int32  `json:"exitCode,omitempty"`
+	Duration  float64 `json:"durationSeconds,omitempty"`
+	// Message explains an Unschedulable phase
+	Message string `json:"message,omitempty"`
+}
+
+// ListResult is the result of the list command
+type ListResult struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Target     string     `json:"target"`
+	Namespace  string     `json:"namespace"`
+	Pods       []PodInfo  `json:"pods"`
+	Nodes      []NodeInfo `json:"nodes"`
+	// ExcludedPods lists the pods dropped by the pod filters
+	ExcludedPods []ExcludedPod `json:"excludedPods,omitempty"`
+	// Revisions groups the pods of a deployment by ReplicaSet, newest revision first
+	Revisions []RevisionInfo `json:"revisions,omitempty"`
+}
+
+// RevisionInfo describes the pods of one ReplicaSet of a deployment
+type RevisionInfo struct {
+	Revision        int64    `json:"revision"`
+	ReplicaSet      string   `json:"replicaSet"`
+	PodTemplateHash string   `json:"podTemplateHash,omitempty"`
+	Current         bool     `json:"current"`
+	Images          []string `json:"images"`
+	Pods            []string `json:"pods"`
+	Nodes           []string `json:"nodes"`
+}
+
+// RunJobResult is the result of the run-job command
+type RunJobResult struct {
+	APIVersion   string     `json:"apiVersion"`
+	Kind         string     `json:"kind"`
+	Target       string     `json:"target"`
+	Namespace    string     `json:"namespace"`
+	JobNamespace string     `json:"jobNamespace"`
+	RunID        string     `json:"runID,omitempty"`
+	Pods         []PodInfo  `json:"pods"`
+	Nodes        []NodeInfo `json:"nodes"`
+	Jobs         []JobInfo  `json:"jobs"`
+	// ExcludedPods lists the pods dropped by the pod filters
+	ExcludedPods []ExcludedPod `json:"excludedPods,omitempty"`
+	// ExcludedNodes lists the target nodes excluded by the node filters
+	ExcludedNodes []ExcludedNode `json:"excludedNodes,omitempty"`
+	// Skipped lists the targets that already had an active job (--if-not-exists)
+	Skipped []SkippedTarget `json:"skipped,omitempty"`
+	// Failures lists the targets whose job could not be created
+	Failures []CreationFailure `json:"failures,omitempty"`
+	// NotAttempted lists the targets skipped after a batched run aborted
+	NotAttempted []string `json:"notAttempted,omitempty"`
+	// DryRun is the --dry-run mode (client or server); no jobs are created in a dry run
+	DryRun string `json:"dryRun,omitempty"`
+	// Tolerations are the distinct tolerations of the job pods in a dry run
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Manifests are the jobs a dry run would create, as returned by the API
+	// server in a server dry run
+	Manifests []batchv1.Job `json:"manifests,omitempty"`
+	// NodeTaints lists the taints of the target nodes and the tolerations added
+	// for them (--tolerate-node-taints)
+	NodeTaints []TaintInfo `json:"nodeTaints,omitempty"`
+}
+
+// TaintInfo describes a taint of a target node and the toleration added for
+// it, or why none was added
+type TaintInfo struct {
+	Node       string             `json:"node"`
+	Taint      string             `json:"taint"`
+	Toleration *corev1.Toleration `json:"toleration,omitempty"`
+	Reason     string             `json:"reason,omitempty"`
+}
+
+// ExcludedPod describes a pod dropped by the pod filters
+type ExcludedPod struct {
+	Pod    string `json:"pod"`
+	Reason string `json:"reason"`
+}
+
+// ExcludedPods converts pod exclusions for output
+func ExcludedPods(exclusions []k8s.PodExclusion) []ExcludedPod {
+	var excluded []ExcludedPod
+	for _, exclusion := range exclusions {
+		excluded = append(excluded, ExcludedPod{Pod: exclusion.Pod, Reason: exclusion.Reason})
+	}
+	return excluded
+}
+
+// ExcludedNode describes a target node excluded by the node filters
+type ExcludedNode struct {
+	Node   string `json:"node"`
+	Reason string `json:"reason"`
+}
+
+// SkippedTarget describes a target skipped because a job for it is still active
+type SkippedTarget struct {
+	Target string `json:"target"`
+	Job    string `json:"job"`
+}
+
+// CreationFailure describes a target whose job could not be created
+type CreationFailure struct {
+	Target   string `json:"target"`
+	Job      string `json:"job"`
+	Category string `json:"category"`
+	Error    string `json:"error"`
+}
+
+// ClusterRunJobResult is the run-job result for a single cluster
+type ClusterRunJobResult struct {
+	Context string        `json:"context"`
+	Error   string        `json:"error,omitempty"`
+	Result  *RunJobResult `json:"result,omitempty"`
+}
+
+// MultiClusterRunJobResult is the result of run-job across several clusters
+type MultiClusterRunJobResult struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Clusters   []ClusterRunJobResult `json:"clusters"`
+}
+
+// PodExecInfo describes the outcome of a command run in one pod
+type PodExecInfo struct {
+	Name      string  `json:"name"`
+	Namespace string  `json:"namespace"`
+	Node      string  `json:"node,omitempty"`
+	Container string  `json:"container"`
+	ExitCode  int     `json:"exitCode"`
+	Error     string  `json:"error,omitempty"`
+	Stdout    string  `json:"stdout"`
+	Stderr    string  `json:"stderr"`
+	Duration  float64 `json:"durationSeconds"`
+}
+
+// ExecResult is the result of the exec command
+type ExecResult struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Target     string        `json:"target"`
+	Namespace  string        `json:"namespace"`
+	Command    []string      `json:"command"`
+	Pods       []PodExecInfo `json:"pods"`
+}
+
+// RunInfo summarizes the jobs of one run
+type RunInfo struct {
+	RunID     string    `json:"runID"`
+	Task      string    `json:"task"`
+	Target    string    `json:"target"`
+	Version   string    `json:"version,omitempty"`
+	Created   time.Time `json:"created"`
+	Jobs      int       `json:"jobs"`
+	Active    int       `json:"active"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+}
+
+// RunListResult is the result of the runs list command
+type RunListResult struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace"`
+	Runs       []RunInfo `json:"runs"`
+}
+
+// RunStatusResult is the result of the runs status command
+type RunStatusResult struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace"`
+	RunID      string    `json:"runID"`
+	Jobs       []JobInfo `json:"jobs"`
+}
+
+// NewListResult builds the list result for the given target pods
+func NewListResult(target, namespace string, pods []corev1.Pod) *ListResult {
+	return &ListResult{
+		APIVersion: APIVersion,
+		Kind:       KindListResult,
+		Target:     target,
+		Namespace:  namespace,
+		Pods:       PodInfos(pods),
+		Nodes:      NodeInfos(pods),
+	}
+}
+
+// NewMultiClusterRunJobResult builds an empty multi-cluster run-job result
+func NewMultiClusterRunJobResult() *MultiClusterRunJobResult {
+	return &MultiClusterRunJobResult{
+		APIVersion: APIVersion,
+		Kind:       KindMultiClusterRunJobResult,
+		Clusters:   []ClusterRunJobResult{},
+	}
+}
+
+// NewRunJobResult builds the run-job result for the given target pods
+func NewRunJobResult(target, namespace, jobNamespace string, pods []corev1.Pod) *RunJobResult {
+	return &RunJobResult{
+		APIVersion:   APIVersion,
+		Kind:         KindRunJobResult,
+		Target:       target,
+		Namespace:    namespace,
+		JobNamespace: jobNamespace,
+		Pods:         PodInfos(pods),
+		Nodes:        NodeInfos(pods),
+		Jobs:         []JobInfo{},
+	}
+}
+
+// NewExecResult builds the exec result from the per-pod outcomes
+func NewExecResult(target, namespace string, command []string, results []k8s.ExecResult) *ExecResult {
+	pods := make([]PodExecInfo, 0, len(results))
+	for _, result := range results {
+		info := PodExecInfo{
+			Name:      result.Pod,
+			Namespace: result.Namespace,
+			Node:      result.Node,
+			Container: result.Container,
+			ExitCode:  result.ExitCode,
+			Stdout:    string(result.Stdout),
+			Stderr:    string(result.Stderr),
+			Duration:  result.Duration.Seconds(),
+		}
+		if result.Err != nil {
+			info.Error = result.Err.Error()
+		}
+		pods = append(pods, info)
+	}
+
+	return &ExecResult{
+		APIVersion: APIVersion,
+		Kind:       KindExecResult,
+		Target:     target,
+		Namespace:  namespace,
+		Command:    command,
+		Pods:       pods,
+	}
+}
+
+// NewRunListResult builds the runs list result
+func NewRunListResult(namespace string, runs []k8s.RunSummary) *RunListResult {
+	infos := make([]RunInfo, 0, len(runs))
+	for _, run := range runs {
+		infos = append(infos, RunInfo{
+			RunID:     run.RunID,
+			Task:      run.Task,
+			Target:    run.Target,
+			Version:   run.Version,
+			Created:   run.Created,
+			Jobs:      run.Jobs,
+			Active:    run.Active,
+			Succeeded: run.Succeeded,
+			Failed:    run.Failed,
+		})
+	}
+
+	return &RunListResult{
+		APIVersion: APIVersion,
+		Kind:       KindRunList,
+		Namespace:  namespace,
+		Runs:       infos,
+	}
+}
+
+// NewRunStatusResult builds the runs status result from the state of its jobs
+func NewRunStatusResult(namespace, runID string, results []k8s.JobResult) *RunStatusResult {
+	jobs := make([]JobInfo, 0, len(results))
+	for _, result := range results {
+		jobs = append(jobs, JobInfo{
+			Name:      result.Job,
+			Namespace: namespace,
+			Node:      result.Node,
+			TargetPod: result.TargetPod,
+			Pod:       result.Pod,
+			Phase:     string(result.Phase),
+			ExitCode:  result.ExitCode,
+			Duration:  result.Duration.Seconds(),
+			Message:   result.Message,
+		})
+	}
+
+	return &RunStatusResult{
+		APIVersion: APIVersion,
+		Kind:       KindRunStatus,
+		Namespace:  namespace,
+		RunID:      runID,
+		Jobs:       jobs,
+	}
+}
+
+// AddJob records a created job
+func (r *RunJobResult) AddJob(name string) {
+	r.Jobs = append(r.Jobs, JobInfo{Name: name, Namespace: r.JobNamespace})
+}
+
+// AddCreations records the outcome of creating jobs, as created jobs or failures
+func (r *RunJobResult) AddCreations(creations []k8s.JobCreation) {
+	for _, c := range creations {
+		if c.Created() {
+			r.AddJob(c.Job)
+			continue
+		}
+		r.AddFailure(c)
+	}
+}
+
+// AddFailure records a job that could not be created
+func (r *RunJobResult) AddFailure(c k8s.JobCreation) {
+	r.Failures = append(r.Failures, CreationFailure{
+		Target:   c.Target(),
+		Job:      c.Job,
+		Category: string(c.Category),
+		Error:    c.Err.Error(),
+	})
+}
+
+// AddExclusions records the target nodes excluded by the node filters
+func (r *RunJobResult) AddExclusions(exclusions []k8s.NodeExclusion) {
+	for _, exclusion := range exclusions {
+		r.ExcludedNodes = append(r.ExcludedNodes, ExcludedNode{Node: exclusion.Node, Reason: exclusion.Reason})
+	}
+}
+
+// AddTaintDecisions records the tolerations generated from node taints
+func (r *RunJobResult) AddTaintDecisions(decisions []k8s.TaintDecision) {
+	for _, decision := range decisions {
+		r.NodeTaints = append(r.NodeTaints, TaintInfo{
+			Node:       decision.Node,
+			Taint:      decision.Taint.ToString(),
+			Toleration: decision.Toleration,
+			Reason:     decision.Reason,
+		})
+	}
+}
+
+// AddManifest records a job that a dry run would create together with the
+// tolerations of its pod that were not seen yet
+func (r *RunJobResult) AddManifest(job *batchv1.Job) {
+	r.Manifests = append(r.Manifests, *job)
+
+	for _, toleration := range job.Spec.Template.Spec.Tolerations {
+		seen := false
+		for _, existing := range r.Tolerations {
+			if reflect.DeepEqual(existing, toleration) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			r.Tolerations = append(r.Tolerations, toleration)
+		}
+	}
+}
+
+// AddDebugContainer records an ephemeral debug container attached to a target pod
+func (r *RunJobResult) AddDebugContainer(ref k8s.EphemeralContainerRef) {
+	r.Jobs = append(r.Jobs, JobInfo{
+		Name:      ref.Container,
+		Namespace: ref.Namespace,
+		Node:      ref.Node,
+		TargetPod: ref.Pod,
+		Pod:       ref.Pod,
+		Container: ref.Container,
+	})
+}
+
+// AddAttachments records the attached debug containers and the pods they
+// could not be attached to
+func (r *RunJobResult) AddAttachments(attachments []k8s.DebugContainerAttachment) {
+	for _, a := range attachments {
+		if a.Attached() {
+			r.AddDebugContainer(a.Ref)
+			continue
+		}
+		r.AddFailure(a.Creation())
+	}
+}
+
+// SetJobResults records the observed outcome of the created jobs
+func (r *RunJobResult) SetJobResults(results []k8s.JobResult) {
+	byName := make(map[string]k8s.JobResult, len(results))
+	for _, result := range results {
+		byName[result.Job] = result
+	}
+
+	for i := range r.Jobs {
+		result, ok := byName[r.Jobs[i].Name]
+		if !ok {
+			continue
+		}
+		r.Jobs[i].Node = result.Node
+		r.Jobs[i].TargetPod = result.TargetPod
+		r.Jobs[i].Pod = result.Pod
+		r.Jobs[i].Phase = string(result.Phase)
+		r.Jobs[i].ExitCode = result.ExitCode
+		r.Jobs[i].Duration = result.Duration.Seconds()
+		r.Jobs[i].Message = result.Message
+	}
+}
+
+// Names returns the target pods as resource names
+func (r *ListResult) Names() []string {
+	names := make([]string, 0, len(r.Pods))
+	for _, pod := range r.Pods {
+		names = append(names, "pod/"+pod.Name)
+	}
+	return names
+}
+
+// Names returns the created jobs (or the pods of debug containers) as resource
+// names. A dry run returns the jobs it would create.
+func (r *RunJobResult) Names() []string {
+	if r.DryRun != "" {
+		names := make([]string, 0, len(r.Manifests))
+		for _, job := range r.Manifests {
+			names = append(names, "job.batch/"+job.Name)
+		}
+		return names
+	}
+
+	names := make([]string, 0, len(r.Jobs))
+	for _, job := range r.Jobs {
+		if job.Container != "" {
+			names = append(names, "pod/"+job.Pod)
+			continue
+		}
+		names = append(names, "job.batch/"+job.Name)
+	}
+	return names
+}
+
+// Names returns the jobs of the run as resource names
+func (r *RunStatusResult) Names() []string {
+	names := make([]string, 0, len(r.Jobs))
+	for _, job := range r.Jobs {
+		names = append(names, "job.batch/"+job.Name)
+	}
+	return names
+}
+
+// Names returns the pods the command ran in as resource names
+func (r *ExecResult) Names() []string {
+	names := make([]string, 0, len(r.Pods))
+	for _, pod := range r.Pods {
+		names = append(names, "pod/"+pod.Name)
+	}
+	return names
+}
+
+// Names returns the created jobs as resource names prefixed with their context
+func (r *MultiClusterRunJobResult) Names() []string {
+	var names []string
+	for _, cluster := range r.Clusters {
+		if cluster.Result == nil {
+			continue
+		}
+		for _, name := range cluster.Result.Names() {
+			names = append(names, cluster.Context+"/"+name)
+		}
+	}
+	return names
+}
+
+// RevisionInfos converts revision groups to their output representation
+func RevisionInfos(groups []k8s.RevisionGroup) []RevisionInfo {
+	infos := make([]RevisionInfo, 0, len(groups))
+	for _, group := range groups {
+		pods := make([]string, 0, len(group.Pods))
+		for _, pod := range group.Pods {
+			pods = append(pods, pod.Name)
+		}
+		nodes := group.Nodes
+		if nodes == nil {
+			nodes = []string{}
+		}
+		infos = append(infos, RevisionInfo{
+			Revision:        group.Revision,
+			ReplicaSet:      group.ReplicaSet,
+			PodTemplateHash: group.Hash,
+			Current:         group.Current,
+			Images:          group.Images,
+			Pods:            pods,
+			Nodes:           nodes,
+		})
+	}
+	return infos
+}
+
+// PodInfos converts pods to their output representation
+func PodInfos(pods []corev1.Pod) []PodInfo {
+	infos := make([]PodInfo, 0, len(pods))
+	for _, pod := range pods {
+		infos = append(infos, PodInfo{
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Node:      pod.Spec.NodeName,
+			Phase:     string(pod.Status.Phase),
+			PodIP:     pod.Status.PodIP,
+			HostIP:    pod.Status.HostIP,
+		})
+	}
+	return infos
+}
+
+// NodeInfos groups scheduled pods by node, sorted by node name
+func NodeInfos(pods []corev1.Pod) []NodeInfo {
+	podsByNode := make(map[string][]string)
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod.Name)
+		}
+	}
+	return nodeInfos(podsByNode)
+}
+
+// TargetNodeInfos groups the pods of job targets by node, sorted by node name.
+// Unlike NodeInfos it only lists the nodes that remain after node filtering.
+func TargetNodeInfos(targets []k8s.JobTarget) []NodeInfo {
+	podsByNode := make(map[string][]string)
+	for _, target := range targets {
+		podNames := podsByNode[target.Node]
+		if podNames == nil {
+			podNames = []string{}
+		}
+		for _, pod := range target.Pods {
+			podNames = append(podNames, pod.Name)
+		}
+		podsByNode[target.Node] = podNames
+	}
+	return nodeInfos(podsByNode)
+}
+
+// nodeInfos converts pod names keyed by node to sorted node infos
+func nodeInfos(podsByNode map[string][]string) []NodeInfo {
+	infos := make([]NodeInfo, 0, len(podsByNode))
+	for node, podNames := range podsByNode {
+		sort.Strings(podNames)
+		infos = append(infos, NodeInfo{Name: node, Pods: podNames})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
@@ -0,0 +1,295 @@
+package output
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/deployment-inspector/pkg/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPods() []corev1.Pod {
+	return []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.2", HostIP: "192.168.0.1"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "node1"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "10.0.0.1", HostIP: "192.168.0.1"},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-3", Namespace: "default"},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	}
+}
+
+func TestNewListResult(t *testing.T) {
+	result := NewListResult("deployment/web", "default", testPods())
+
+	if result.APIVersion != APIVersion || result.Kind != KindListResult {
+		t.Errorf("Unexpected apiVersion/kind %s/%s", result.APIVersion, result.Kind)
+	}
+	if len(result.Pods) != 3 {
+		t.Fatalf("Expected 3 pods, got %d", len(result.Pods))
+	}
+	if result.Pods[0].PodIP != "10.0.0.2" || result.Pods[0].HostIP != "192.168.0.1" || result.Pods[0].Phase != "Running" {
+		t.Errorf("Unexpected pod info %+v", result.Pods[0])
+	}
+
+	// Unscheduled pods are not attributed to any node
+	if len(result.Nodes) != 1 {
+		t.Fatalf("Expected 1 node, got %d", len(result.Nodes))
+	}
+	if result.Nodes[0].Name != "node1" {
+		t.Errorf("Expected node1, got %s", result.Nodes[0].Name)
+	}
+	if len(result.Nodes[0].Pods) != 2 || result.Nodes[0].Pods[0] != "web-1" {
+		t.Errorf("Expected sorted pods [web-1 web-2], got %v", result.Nodes[0].Pods)
+	}
+}
+
+func TestRunJobResult_SetJobResults(t *testing.T) {
+	result := NewRunJobResult("deployment/web", "default", "jobs", testPods())
+	result.AddJob("task-1")
+	result.AddJob("task-2")
+
+	exitCode := int32(1)
+	result.SetJobResults([]k8s.JobResult{
+		{Node: "node1", Job: "task-2", Pod: "task-2-x", Phase: k8s.JobPhaseFailed, ExitCode: &exitCode, Duration: 3 * time.Second},
+	})
+
+	if result.Jobs[0].Phase != "" {
+		t.Errorf("Expected no phase for task-1, got %s", result.Jobs[0].Phase)
+	}
+	job := result.Jobs[1]
+	if job.Namespace != "jobs" || job.Node != "node1" || job.Pod != "task-2-x" || job.Phase != "Failed" {
+		t.Errorf("Unexpected job info %+v", job)
+	}
+	if job.ExitCode == nil || *job.ExitCode != 1 {
+		t.Errorf("Expected exit code 1, got %v", job.ExitCode)
+	}
+	if job.Duration != 3 {
+		t.Errorf("Expected duration 3s, got %v", job.Duration)
+	}
+
+	names := result.Names()
+	if len(names) != 2 || names[0] != "job.batch/task-1" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestMultiClusterRunJobResult_Names(t *testing.T) {
+	east := NewRunJobResult("deployment/web", "default", "default", nil)
+	east.AddJob("task-1")
+
+	result := NewMultiClusterRunJobResult()
+	result.Clusters = append(result.Clusters,
+		ClusterRunJobResult{Context: "east", Result: east},
+		ClusterRunJobResult{Context: "west", Error: "connection refused"},
+	)
+
+	names := result.Names()
+	if len(names) != 1 || names[0] != "east/job.batch/task-1" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestRunJobResult_AddDebugContainer(t *testing.T) {
+	result := NewRunJobResult("deployment/web", "default", "default", testPods())
+	result.AddDebugContainer(k8s.EphemeralContainerRef{Namespace: "default", Pod: "web-1", Node: "node1", Container: "debug-123456"})
+
+	exitCode := int32(0)
+	result.SetJobResults([]k8s.JobResult{
+		{Node: "node1", TargetPod: "web-1", Job: "debug-123456", Pod: "web-1", Container: "debug-123456", Phase: k8s.JobPhaseSucceeded, ExitCode: &exitCode},
+	})
+
+	job := result.Jobs[0]
+	if job.Container != "debug-123456" || job.TargetPod != "web-1" || job.Phase != "Succeeded" {
+		t.Errorf("Unexpected job info %+v", job)
+	}
+
+	names := result.Names()
+	if len(names) != 1 || names[0] != "pod/web-1" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestRunJobResult_AddAttachments(t *testing.T) {
+	result := NewRunJobResult("deployment/web", "default", "default", testPods())
+	result.AddAttachments([]k8s.DebugContainerAttachment{
+		{Ref: k8s.EphemeralContainerRef{Namespace: "default", Pod: "web-1", Node: "node1", Container: "debug-abc123"}},
+		{Ref: k8s.EphemeralContainerRef{Namespace: "default", Pod: "web-2", Node: "node2", Container: "debug-abc123"}, Err: fmt.Errorf("pods is forbidden"), Category: k8s.ErrorCategoryForbidden},
+	})
+
+	if len(result.Jobs) != 1 || result.Jobs[0].TargetPod != "web-1" {
+		t.Errorf("Unexpected jobs %+v", result.Jobs)
+	}
+	want := CreationFailure{Target: "node2/web-2", Job: "debug-abc123", Category: "Forbidden", Error: "pods is forbidden"}
+	if len(result.Failures) != 1 || result.Failures[0] != want {
+		t.Errorf("Expected failures [%+v], got %+v", want, result.Failures)
+	}
+}
+
+func TestRunJobResult_AddCreations(t *testing.T) {
+	result := NewRunJobResult("deployment/web", "default", "jobs", testPods())
+	result.AddCreations([]k8s.JobCreation{
+		{Node: "node1", Job: "task-000001"},
+		{Node: "node2", TargetPod: "web-2", Job: "task-000002", Err: fmt.Errorf("jobs is forbidden"), Category: k8s.ErrorCategoryForbidden},
+	})
+
+	if len(result.Jobs) != 1 || result.Jobs[0].Name != "task-000001" || result.Jobs[0].Namespace != "jobs" {
+		t.Errorf("Unexpected jobs %+v", result.Jobs)
+	}
+	expected := CreationFailure{Target: "node2/web-2", Job: "task-000002", Category: "Forbidden", Error: "jobs is forbidden"}
+	if len(result.Failures) != 1 || result.Failures[0] != expected {
+		t.Errorf("Expected failures [%+v], got %+v", expected, result.Failures)
+	}
+}
+
+func TestRunJobResult_AddManifest(t *testing.T) {
+	result := NewRunJobResult("deployment/web", "default", "jobs", testPods())
+	result.DryRun = "client"
+
+	toleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "web", Effect: corev1.TaintEffectNoSchedule}
+	for _, name := range []string{"task-000001", "task-000002"} {
+		job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "jobs"}}
+		job.Spec.Template.Spec.Tolerations = []corev1.Toleration{toleration}
+		result.AddManifest(job)
+	}
+
+	if len(result.Tolerations) != 1 || result.Tolerations[0] != toleration {
+		t.Errorf("Expected tolerations [%+v], got %+v", toleration, result.Tolerations)
+	}
+	names := result.Names()
+	if len(names) != 2 || names[0] != "job.batch/task-000001" || names[1] != "job.batch/task-000002" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestRevisionInfos(t *testing.T) {
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-bbb-1"}}
+	infos := RevisionInfos([]k8s.RevisionGroup{
+		{ReplicaSet: "web-ccc", Revision: 3, Hash: "ccc", Current: true, Images: []string{"nginx:1.25"}},
+		{ReplicaSet: "web-bbb", Revision: 2, Hash: "bbb", Images: []string{"nginx:1.24"}, Pods: []corev1.Pod{pod}, Nodes: []string{"node1"}},
+	})
+
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 revisions, got %+v", infos)
+	}
+	// Empty revisions report empty lists rather than null
+	if !infos[0].Current || infos[0].Pods == nil || infos[0].Nodes == nil {
+		t.Errorf("Unexpected newest revision %+v", infos[0])
+	}
+	if infos[1].Revision != 2 || infos[1].PodTemplateHash != "bbb" || len(infos[1].Pods) != 1 || infos[1].Pods[0] != "web-bbb-1" {
+		t.Errorf("Unexpected old revision %+v", infos[1])
+	}
+}
+
+func TestExcludedPods(t *testing.T) {
+	excluded := ExcludedPods([]k8s.PodExclusion{{Pod: "web-1", Reason: "pod is not ready"}})
+	if len(excluded) != 1 || excluded[0] != (ExcludedPod{Pod: "web-1", Reason: "pod is not ready"}) {
+		t.Errorf("Unexpected excluded pods %+v", excluded)
+	}
+	if ExcludedPods(nil) != nil {
+		t.Error("Expected nil for no exclusions so that excludedPods is omitted")
+	}
+}
+
+func TestTargetNodeInfos(t *testing.T) {
+	infos := TargetNodeInfos(k8s.NodeTargets(testPods()))
+
+	if len(infos) != 1 || infos[0].Name != "node1" {
+		t.Fatalf("Expected only node1, got %+v", infos)
+	}
+	if len(infos[0].Pods) != 2 || infos[0].Pods[0] != "web-1" {
+		t.Errorf("Expected sorted pods [web-1 web-2], got %v", infos[0].Pods)
+	}
+
+	// Targets without pods still list their node
+	infos = TargetNodeInfos([]k8s.JobTarget{{Node: "node3"}})
+	if len(infos) != 1 || infos[0].Name != "node3" || infos[0].Pods == nil {
+		t.Errorf("Unexpected node infos %+v", infos)
+	}
+}
+
+func TestRunJobResult_AddExclusions(t *testing.T) {
+	result := NewRunJobResult("deployment/web", "default", "jobs", testPods())
+	result.AddExclusions([]k8s.NodeExclusion{{Node: "node2", Reason: "node is cordoned"}})
+
+	if len(result.ExcludedNodes) != 1 || result.ExcludedNodes[0] != (ExcludedNode{Node: "node2", Reason: "node is cordoned"}) {
+		t.Errorf("Unexpected excluded nodes %+v", result.ExcludedNodes)
+	}
+}
+
+func TestRunJobResult_AddTaintDecisions(t *testing.T) {
+	result := NewRunJobResult("deployment/web", "default", "jobs", testPods())
+	toleration := corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "true", Effect: corev1.TaintEffectNoSchedule}
+
+	result.AddTaintDecisions([]k8s.TaintDecision{
+		{Node: "node1", Taint: corev1.Taint{Key: "gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}, Toleration: &toleration},
+		{Node: "node1", Taint: corev1.Taint{Key: "dedicated", Effect: corev1.TaintEffectNoExecute}, Reason: "key is denied"},
+	})
+
+	expected := []TaintInfo{
+		{Node: "node1", Taint: "gpu=true:NoSchedule", Toleration: &toleration},
+		{Node: "node1", Taint: "dedicated:NoExecute", Reason: "key is denied"},
+	}
+	if len(result.NodeTaints) != len(expected) {
+		t.Fatalf("Expected %d node taints, got %+v", len(expected), result.NodeTaints)
+	}
+	for i, want := range expected {
+		if result.NodeTaints[i] != want {
+			t.Errorf("Expected %+v, got %+v", want, result.NodeTaints[i])
+		}
+	}
+}
+
+func TestNewExecResult(t *testing.T) {
+	result := NewExecResult("deployment/web", "default", []string{"cat", "/etc/config"}, []k8s.ExecResult{
+		{Pod: "web-1", Namespace: "default", Node: "node1", Container: "app", Stdout: []byte("ok\n"), Duration: 2 * time.Second},
+		{Pod: "web-2", Namespace: "default", Node: "node2", Container: "app", ExitCode: -1, Err: fmt.Errorf("connection refused")},
+	})
+
+	if result.Kind != KindExecResult || len(result.Pods) != 2 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if result.Pods[0].Stdout != "ok\n" || result.Pods[0].Duration != 2 || result.Pods[0].Error != "" {
+		t.Errorf("Unexpected pod info %+v", result.Pods[0])
+	}
+	if result.Pods[1].ExitCode != -1 || result.Pods[1].Error != "connection refused" {
+		t.Errorf("Unexpected pod info %+v", result.Pods[1])
+	}
+
+	names := result.Names()
+	if len(names) != 2 || names[1] != "pod/web-2" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestNewRunStatusResult(t *testing.T) {
+	exitCode := int32(1)
+	result := NewRunStatusResult("jobs", "abc", []k8s.JobResult{
+		{Node: "node1", Job: "inspect-abc-1", Pod: "inspect-abc-1-xyz", Phase: k8s.JobPhaseFailed, ExitCode: &exitCode, Duration: 3 * time.Second},
+		{Node: "node2", Job: "inspect-abc-2", Phase: k8s.JobPhasePending},
+	})
+
+	if result.Kind != KindRunStatus || result.RunID != "abc" || len(result.Jobs) != 2 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	job := result.Jobs[0]
+	if job.Namespace != "jobs" || job.Node != "node1" || job.Phase != "Failed" || *job.ExitCode != 1 || job.Duration != 3 {
+		t.Errorf("Unexpected job info %+v", job)
+	}
+
+	names := result.Names()
+	if len(names) != 2 || names[1] != "job.batch/inspect-abc-2" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}